@@ -0,0 +1,204 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/config"
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// flagFields copy the value of a flag from one configuration to another, by the flag's name.
+// Flags of secrets also clear the other ways the file may set the secret, so the flag replaces them.
+var flagFields = map[string]func(dst, src *config.Config){
+	"alertmanager.bearer-token-file": func(dst, src *config.Config) {
+		am := alertmanagerConfig(dst, src)
+		am.BearerToken = ""
+		am.BearerTokenFile = src.Alertmanagers[0].BearerTokenFile
+		am.BasicAuth = config.BasicAuthConfig{}
+	},
+	"alertmanager.url": func(dst, src *config.Config) {
+		alertmanagerConfig(dst, src).URL = src.Alertmanagers[0].URL
+	},
+	"bolt.path":                  func(dst, src *config.Config) { dst.Store.Bolt.Path = src.Store.Bolt.Path },
+	"consul.url":                 func(dst, src *config.Config) { dst.Store.Consul.URL = src.Store.Consul.URL },
+	"listen.addr":                func(dst, src *config.Config) { dst.ListenAddr = src.ListenAddr },
+	"store":                      func(dst, src *config.Config) { dst.Store.Type = src.Store.Type },
+	"telegram.admin":             func(dst, src *config.Config) { dst.Telegram.Admins = src.Telegram.Admins },
+	"telegram.alerts-page-size":  func(dst, src *config.Config) { dst.Telegram.AlertsPageSize = src.Telegram.AlertsPageSize },
+	"telegram.attachment-format": func(dst, src *config.Config) { dst.Telegram.AttachmentFormat = src.Telegram.AttachmentFormat },
+	"telegram.attachment-size":   func(dst, src *config.Config) { dst.Telegram.AttachmentSize = src.Telegram.AttachmentSize },
+	"telegram.max-messages":      func(dst, src *config.Config) { dst.Telegram.MaxMessages = src.Telegram.MaxMessages },
+	"telegram.queue-size":        func(dst, src *config.Config) { dst.Telegram.QueueSize = src.Telegram.QueueSize },
+	"telegram.token": func(dst, src *config.Config) {
+		dst.Telegram.Token, dst.Telegram.TokenFile = src.Telegram.Token, ""
+	},
+	"telegram.token-file": func(dst, src *config.Config) {
+		dst.Telegram.Token, dst.Telegram.TokenFile = "", src.Telegram.TokenFile
+	},
+	"telegram.webhook-path": func(dst, src *config.Config) { dst.Telegram.Webhook.Path = src.Telegram.Webhook.Path },
+	"telegram.webhook-secret-token": func(dst, src *config.Config) {
+		dst.Telegram.Webhook.SecretToken = src.Telegram.Webhook.SecretToken
+	},
+	"telegram.webhook-url": func(dst, src *config.Config) { dst.Telegram.Webhook.URL = src.Telegram.Webhook.URL },
+	"telegram.workers":     func(dst, src *config.Config) { dst.Telegram.Workers = src.Telegram.Workers },
+	"template.paths":       func(dst, src *config.Config) { dst.Templates.Paths = src.Templates.Paths },
+	"template.watch":       func(dst, src *config.Config) { dst.Templates.Watch = src.Templates.Watch },
+	"webhook.bearer-token": func(dst, src *config.Config) {
+		dst.Webhook.BearerToken, dst.Webhook.BearerTokenFile = src.Webhook.BearerToken, ""
+	},
+	"webhook.bearer-token-file": func(dst, src *config.Config) {
+		dst.Webhook.BearerToken, dst.Webhook.BearerTokenFile = "", src.Webhook.BearerTokenFile
+	},
+}
+
+// secretFlags are the flags of secrets the configuration file can set in other ways too
+var secretFlags = []string{
+	"alertmanager.bearer-token-file",
+	"telegram.token",
+	"telegram.token-file",
+	"webhook.bearer-token",
+	"webhook.bearer-token-file",
+}
+
+// alertmanagerConfig returns the alertmanager of dst, which is the one of src if dst has none
+func alertmanagerConfig(dst, src *config.Config) *config.AlertmanagerConfig {
+	if len(dst.Alertmanagers) == 0 {
+		dst.Alertmanagers = append(dst.Alertmanagers, src.Alertmanagers[0])
+	}
+	return &dst.Alertmanagers[0]
+}
+
+// setFlags returns the names of the flags of the command that were set on the command line
+// or by their environment variable, as opposed to having their default value.
+func setFlags(a *kingpin.Application, cmd *kingpin.CmdClause, args []string) (map[string]bool, error) {
+	ctx, err := a.ParseContext(args)
+	if err != nil {
+		return nil, err
+	}
+
+	set := map[string]bool{}
+	for _, e := range ctx.Elements {
+		if f, ok := e.Clause.(*kingpin.FlagClause); ok {
+			set[f.Model().Name] = true
+		}
+	}
+	for _, f := range append(a.Model().Flags, cmd.Model().Flags...) {
+		if f.Envar != "" && os.Getenv(f.Envar) != "" {
+			set[f.Name] = true
+		}
+	}
+
+	return set, nil
+}
+
+// loadConfig loads the configuration file on top of the flags' values,
+// so flags that were set override the file and the file overrides the flags' defaults.
+func loadConfig(path string, flags config.Config, set map[string]bool) (config.Config, error) {
+	cfg := flags
+	cfg.Alertmanagers = append([]config.AlertmanagerConfig(nil), flags.Alertmanagers...)
+	if path != "" {
+		// Set secret flags are applied after the file, leave them out while it's validated,
+		// so a flag like --telegram.token doesn't conflict with the file's token_file.
+		unset := config.Config{Alertmanagers: []config.AlertmanagerConfig{{}}}
+		for _, name := range secretFlags {
+			if set[name] {
+				flagFields[name](&cfg, &unset)
+			}
+		}
+
+		if err := config.LoadFile(path, &cfg); err != nil {
+			return cfg, err
+		}
+		for name := range set {
+			if field, ok := flagFields[name]; ok {
+				field(&cfg, &flags)
+			}
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return cfg, err
+	}
+	return cfg, validateConfig(cfg)
+}
+
+// validateConfig returns an error for values only the bot knows are invalid,
+// like unknown attachment formats and route names it can't use
+func validateConfig(cfg config.Config) error {
+	switch cfg.Telegram.AttachmentFormat {
+	case "", telegram.AttachmentText, telegram.AttachmentHTML, telegram.AttachmentCSV:
+	default:
+		return fmt.Errorf("unknown attachment format %q, choose from %s, %s or %s", cfg.Telegram.AttachmentFormat,
+			telegram.AttachmentText, telegram.AttachmentHTML, telegram.AttachmentCSV)
+	}
+	for _, r := range cfg.Routes {
+		if err := telegram.ValidRouteName(r.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// requireConfig returns an error if a value that has no default is missing or the templates don't exist
+func requireConfig(cfg config.Config) error {
+	if len(cfg.Alertmanagers) == 0 {
+		return fmt.Errorf("an alertmanager is required, pass --alertmanager.url or set alertmanagers in the config file")
+	}
+	if cfg.Store.Type == "" {
+		return fmt.Errorf("a store is required, pass --store or set store.type in the config file")
+	}
+	if cfg.Telegram.Token == "" && cfg.Telegram.TokenFile == "" {
+		return fmt.Errorf("a telegram token is required, pass --telegram.token or --telegram.token-file or set telegram.token or telegram.token_file in the config file")
+	}
+	if len(cfg.Telegram.Admins) == 0 {
+		return fmt.Errorf("a telegram admin is required, pass --telegram.admin or set telegram.admins in the config file")
+	}
+	for _, p := range cfg.Templates.Paths {
+		if files, err := filepath.Glob(p); err != nil || len(files) == 0 {
+			return fmt.Errorf("template path %s matches no files", p)
+		}
+	}
+	return nil
+}
+
+// staticRoutes returns the routes of the configuration for the bot
+func staticRoutes(cfg config.Config) []telegram.Route {
+	routes := make([]telegram.Route, 0, len(cfg.Routes))
+	for _, r := range cfg.Routes {
+		routes = append(routes, telegram.Route{Name: r.Name, ChatIDs: r.Chats})
+	}
+	return routes
+}
+
+// checkConfigFile validates the configuration file and parses its templates, if it has some,
+// and writes the result to w.
+func checkConfigFile(w io.Writer, path string) error {
+	var cfg config.Config
+	if err := config.LoadFile(path, &cfg); err != nil {
+		return err
+	}
+	if err := validateConfig(cfg); err != nil {
+		return fmt.Errorf("failed to load %s: %v", path, err)
+	}
+
+	if len(cfg.Templates.Paths) > 0 {
+		externalURL := &url.URL{}
+		if len(cfg.Alertmanagers) > 0 {
+			externalURL = cfg.Alertmanagers[0].URL.URL
+		}
+		tmpl, err := telegram.NewTemplates(externalURL, cfg.Templates.Paths...)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "templates: %s\n", strings.Join(tmpl.Names(), ", "))
+	}
+
+	fmt.Fprintf(w, "%s is valid\n", path)
+	return nil
+}
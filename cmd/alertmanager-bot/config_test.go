@@ -0,0 +1,74 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateConfig(t *testing.T) {
+	var cfg config.Config
+	cfg.Telegram.AttachmentFormat = "csv"
+	cfg.Routes = []config.RouteConfig{{Name: "team.db", Chats: []int64{1}}}
+	assert.NoError(t, validateConfig(cfg))
+
+	cfg.Telegram.AttachmentFormat = "pdf"
+	assert.EqualError(t, validateConfig(cfg), `unknown attachment format "pdf", choose from txt, html or csv`)
+
+	cfg.Telegram.AttachmentFormat = ""
+	cfg.Routes = []config.RouteConfig{{Name: "team/db", Chats: []int64{1}}}
+	assert.Error(t, validateConfig(cfg))
+}
+
+func TestLoadConfigSecretFlags(t *testing.T) {
+	dir, err := ioutil.TempDir("", "alertmanager-bot")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	tokens := filepath.Join(dir, "tokens.yml")
+	err = ioutil.WriteFile(tokens, []byte("telegram:\n  token: file\nwebhook:\n  bearer_token: file\n"), 0600)
+	assert.NoError(t, err)
+	files := filepath.Join(dir, "files.yml")
+	err = ioutil.WriteFile(files, []byte("telegram:\n  token_file: /token\nwebhook:\n  bearer_token_file: /bearer\n"), 0600)
+	assert.NoError(t, err)
+
+	var flags config.Config
+	flags.Telegram.TokenFile = "/flag/token"
+	flags.Webhook.BearerTokenFile = "/flag/bearer"
+	cfg, err := loadConfig(tokens, flags, map[string]bool{"telegram.token-file": true, "webhook.bearer-token-file": true})
+	assert.NoError(t, err)
+	assert.Equal(t, config.TelegramConfig{TokenFile: "/flag/token"}, cfg.Telegram)
+	assert.Equal(t, config.AuthConfig{BearerTokenFile: "/flag/bearer"}, cfg.Webhook.AuthConfig)
+
+	flags = config.Config{}
+	flags.Telegram.Token = "flag"
+	flags.Webhook.BearerToken = "flag"
+	cfg, err = loadConfig(files, flags, map[string]bool{"telegram.token": true, "webhook.bearer-token": true})
+	assert.NoError(t, err)
+	assert.Equal(t, config.TelegramConfig{Token: "flag"}, cfg.Telegram)
+	assert.Equal(t, config.AuthConfig{BearerToken: "flag"}, cfg.Webhook.AuthConfig)
+
+	alertmanager := filepath.Join(dir, "alertmanager.yml")
+	err = ioutil.WriteFile(alertmanager, []byte("alertmanagers:\n  - url: http://localhost:9093\n    basic_auth:\n      username: bot\n"), 0600)
+	assert.NoError(t, err)
+
+	u, err := url.Parse("http://alertmanager:9093")
+	assert.NoError(t, err)
+	flags = config.Config{Alertmanagers: []config.AlertmanagerConfig{{URL: config.URL{URL: u}}}}
+	flags.Alertmanagers[0].BearerTokenFile = "/flag/alertmanager"
+	cfg, err = loadConfig(alertmanager, flags, map[string]bool{"alertmanager.bearer-token-file": true})
+	assert.NoError(t, err)
+	assert.Equal(t, config.AuthConfig{BearerTokenFile: "/flag/alertmanager"}, cfg.Alertmanagers[0].AuthConfig)
+	assert.Equal(t, "http://localhost:9093", cfg.Alertmanagers[0].URL.String())
+	// the flags are loaded again on reloads
+	assert.Equal(t, "/flag/alertmanager", flags.Alertmanagers[0].BearerTokenFile)
+
+	cfg, err = loadConfig(files, flags, map[string]bool{"alertmanager.url": true})
+	assert.NoError(t, err)
+	assert.Equal(t, "http://alertmanager:9093", cfg.Alertmanagers[0].URL.String())
+}
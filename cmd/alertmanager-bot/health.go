@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// readyTimeout limits how long the readiness checks may take together
+const readyTimeout = 5 * time.Second
+
+// checkResult is the result of a single readiness check
+type checkResult struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// readiness is the JSON response of /-/ready
+type readiness struct {
+	Status string                 `json:"status"`
+	Checks map[string]checkResult `json:"checks"`
+}
+
+// handleHealthy answers liveness probes, the bot is alive as long as it serves requests
+func handleHealthy(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// handleReady answers readiness probes with the results of the checks,
+// it responds with 503 Service Unavailable if any of them failed.
+func handleReady(checks func(ctx context.Context) map[string]error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+		defer cancel()
+
+		resp := readiness{Status: "ok", Checks: map[string]checkResult{}}
+		for name, err := range checks(ctx) {
+			if err != nil {
+				resp.Status = "degraded"
+				resp.Checks[name] = checkResult{Status: "failed", Error: err.Error()}
+				continue
+			}
+			resp.Checks[name] = checkResult{Status: "ok"}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if resp.Status != "ok" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(resp)
+	}
+}
@@ -7,31 +7,30 @@ import (
 	"net/url"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/docker/libkv/store"
 	"github.com/docker/libkv/store/boltdb"
 	"github.com/docker/libkv/store/consul"
+	"github.com/fsnotify/fsnotify"
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
-	"github.com/hako/durafmt"
 	"github.com/joho/godotenv"
 	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/metalmatze/alertmanager-bot/pkg/config"
 	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
 	"github.com/oklog/run"
-	"github.com/prometheus/alertmanager/notify"
-	"github.com/prometheus/alertmanager/template"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"gopkg.in/alecthomas/kingpin.v2"
 )
 
 const (
-	storeBolt   = "bolt"
-	storeConsul = "consul"
-
 	levelDebug = "debug"
 	levelInfo  = "info"
 	levelWarn  = "warn"
@@ -54,77 +53,174 @@ var (
 func main() {
 	godotenv.Load()
 
-	config := struct {
-		alertmanager   *url.URL
-		boltPath       string
-		consul         *url.URL
-		listenAddr     string
-		logLevel       string
-		logJSON        bool
-		store          string
-		telegramAdmins []int
-		telegramToken  string
-		templatesPaths []string
+	// flags are the configuration passed as flags, the configuration file is loaded on top of them
+	var flags config.Config
+
+	app := struct {
+		alertmanager          *url.URL
+		alertmanagerTokenFile string
+		configFile            string
+		logLevel              string
+		logJSON               bool
+		enableLifecycle       bool
+	}{}
+
+	checkConfig := struct {
+		files []string
+		data  string
 	}{}
 
 	a := kingpin.New("alertmanager-bot", "Bot for Prometheus' Alertmanager")
 	a.HelpFlag.Short('h')
 
+	r := a.Command("run", "Run the bot").Default()
+
+	check := a.Command("template", "Work with templates").
+		Command("check", "Check templates by rendering them with sample alerts or a webhook")
+
+	configCheck := a.Command("config", "Work with the configuration file").
+		Command("check", "Check the configuration file passed with --config.file and its templates")
+
+	check.Flag("file", "The template files to check").
+		Required().
+		ExistingFilesVar(&checkConfig.files)
+
+	check.Flag("data", "A webhook sent by Alertmanager as JSON to render instead of the sample alerts").
+		ExistingFileVar(&checkConfig.data)
+
 	a.Flag("alertmanager.url", "The URL that's used to connect to the alertmanager").
 		Envar("ALERTMANAGER_URL").
 		Default("http://localhost:9093/").
-		URLVar(&config.alertmanager)
+		URLVar(&app.alertmanager)
+
+	r.Flag("alertmanager.bearer-token-file", "The file with the bearer token requests to the alertmanager are sent with, read again for every request").
+		Envar("ALERTMANAGER_BEARER_TOKEN_FILE").
+		StringVar(&app.alertmanagerTokenFile)
 
-	a.Flag("bolt.path", "The path to the file where bolt persists its data").
+	a.Flag("config.file", "The configuration file, flags and environment variables that are set override its values").
+		Envar("CONFIG_FILE").
+		ExistingFileVar(&app.configFile)
+
+	r.Flag("bolt.path", "The path to the file where bolt persists its data").
 		Envar("BOLT_PATH").
 		Default("/tmp/bot.db").
-		StringVar(&config.boltPath)
+		StringVar(&flags.Store.Bolt.Path)
 
-	a.Flag("consul.url", "The URL that's used to connect to the consul store").
+	r.Flag("consul.url", "The URL that's used to connect to the consul store").
 		Envar("CONSUL_URL").
 		Default("localhost:8500").
-		URLVar(&config.consul)
+		URLVar(&flags.Store.Consul.URL.URL)
 
-	a.Flag("listen.addr", "The address the alertmanager-bot listens on for incoming webhooks").
+	r.Flag("listen.addr", "The address the alertmanager-bot listens on for incoming webhooks").
 		Envar("LISTEN_ADDR").
 		Default("0.0.0.0:8080").
-		StringVar(&config.listenAddr)
+		StringVar(&flags.ListenAddr)
 
 	a.Flag("log.json", "Tell the application to log json and not key value pairs").
 		Envar("LOG_JSON").
-		BoolVar(&config.logJSON)
+		BoolVar(&app.logJSON)
 
 	a.Flag("log.level", "The log level to use for filtering logs").
 		Envar("LOG_LEVEL").
 		Default(levelInfo).
-		EnumVar(&config.logLevel, levelError, levelWarn, levelInfo, levelDebug)
+		EnumVar(&app.logLevel, levelError, levelWarn, levelInfo, levelDebug)
 
-	a.Flag("store", "The store to use").
-		Required().
+	r.Flag("store", "The store to use").
 		Envar("STORE").
-		EnumVar(&config.store, storeBolt, storeConsul)
+		EnumVar(&flags.Store.Type, config.StoreBolt, config.StoreConsul)
 
-	a.Flag("telegram.admin", "The ID of the initial Telegram Admin").
-		Required().
+	r.Flag("telegram.admin", "The ID of the initial Telegram Admin").
 		Envar("TELEGRAM_ADMIN").
-		IntsVar(&config.telegramAdmins)
-
-	a.Flag("telegram.token", "The token used to connect with Telegram").
-		Required().
+		IntsVar(&flags.Telegram.Admins)
+
+	r.Flag("telegram.alerts-page-size", "The number of alerts shown on each page of /alerts").
+		Envar("TELEGRAM_ALERTS_PAGE_SIZE").
+		Default("10").
+		IntVar(&flags.Telegram.AlertsPageSize)
+
+	r.Flag("telegram.attachment-format", "The format of files that long /alerts replies are sent as").
+		Envar("TELEGRAM_ATTACHMENT_FORMAT").
+		Default(telegram.AttachmentText).
+		EnumVar(&flags.Telegram.AttachmentFormat, telegram.AttachmentText, telegram.AttachmentHTML, telegram.AttachmentCSV)
+
+	r.Flag("telegram.attachment-size", "The length in characters above which /alerts replies are sent as a file, 0 to disable").
+		Envar("TELEGRAM_ATTACHMENT_SIZE").
+		Default("0").
+		IntVar(&flags.Telegram.AttachmentSize)
+
+	r.Flag("telegram.max-messages", "The number of messages a long message is split into before it's sent as a file instead, 0 to never send files").
+		Envar("TELEGRAM_MAX_MESSAGES").
+		Default("5").
+		IntVar(&flags.Telegram.MaxMessages)
+
+	r.Flag("telegram.queue-size", "The number of notifications waiting to be sent at most, further ones are dropped").
+		Envar("TELEGRAM_QUEUE_SIZE").
+		Default("1000").
+		IntVar(&flags.Telegram.QueueSize)
+
+	r.Flag("telegram.token", "The token used to connect with Telegram").
 		Envar("TELEGRAM_TOKEN").
-		StringVar(&config.telegramToken)
+		StringVar(&flags.Telegram.Token)
+
+	r.Flag("telegram.token-file", "The file with the token used to connect with Telegram, read again every 30 seconds to switch to a changed token without restarting").
+		Envar("TELEGRAM_TOKEN_FILE").
+		StringVar(&flags.Telegram.TokenFile)
 
-	a.Flag("template.paths", "The paths to the template").
+	r.Flag("telegram.webhook-path", "The path Telegram's updates are received on, with --telegram.webhook-url").
+		Envar("TELEGRAM_WEBHOOK_PATH").
+		Default("/telegram").
+		StringVar(&flags.Telegram.Webhook.Path)
+
+	r.Flag("telegram.webhook-secret-token", "The secret token Telegram sends updates with, random if not set").
+		Envar("TELEGRAM_WEBHOOK_SECRET_TOKEN").
+		StringVar(&flags.Telegram.Webhook.SecretToken)
+
+	r.Flag("telegram.webhook-url", "The public HTTPS URL Telegram sends updates to instead of the bot polling them").
+		Envar("TELEGRAM_WEBHOOK_URL").
+		URLVar(&flags.Telegram.Webhook.URL.URL)
+
+	r.Flag("telegram.workers", "The number of chats notifications are sent to at the same time").
+		Envar("TELEGRAM_WORKERS").
+		Default("4").
+		IntVar(&flags.Telegram.Workers)
+
+	r.Flag("template.paths", "The paths to the templates, every template defined as telegram.<name> can be chosen with /template").
 		Envar("TEMPLATE_PATHS").
 		Default("/templates/default.tmpl").
-		ExistingFilesVar(&config.templatesPaths)
+		StringsVar(&flags.Templates.Paths)
+
+	r.Flag("template.watch", "Reload the templates when their files change").
+		Envar("TEMPLATE_WATCH").
+		BoolVar(&flags.Templates.Watch)
+
+	r.Flag("webhook.bearer-token", "The bearer token Alertmanager has to send webhooks with").
+		Envar("WEBHOOK_BEARER_TOKEN").
+		StringVar(&flags.Webhook.BearerToken)
+
+	r.Flag("webhook.bearer-token-file", "The file with the bearer token Alertmanager has to send webhooks with, read again for every webhook").
+		Envar("WEBHOOK_BEARER_TOKEN_FILE").
+		StringVar(&flags.Webhook.BearerTokenFile)
+
+	r.Flag("web.enable-lifecycle", "Reload the configuration file and templates by POST requests to /-/reload").
+		Envar("WEB_ENABLE_LIFECYCLE").
+		BoolVar(&app.enableLifecycle)
 
-	_, err := a.Parse(os.Args[1:])
+	cmd, err := a.Parse(os.Args[1:])
 	if err != nil {
 		fmt.Printf("error parsing commandline arguments: %v\n", err)
 		a.Usage(os.Args[1:])
 		os.Exit(2)
 	}
+	flags.Alertmanagers = []config.AlertmanagerConfig{{
+		URL:        config.URL{URL: app.alertmanager},
+		AuthConfig: config.AuthConfig{BearerTokenFile: app.alertmanagerTokenFile},
+	}}
+
+	set, err := setFlags(a, r, os.Args[1:])
+	if err != nil {
+		fmt.Printf("error parsing commandline arguments: %v\n", err)
+		os.Exit(2)
+	}
 
 	levelFilter := map[string]level.Option{
 		levelError: level.AllowError(),
@@ -134,47 +230,82 @@ func main() {
 	}
 
 	logger := log.NewLogfmtLogger(log.NewSyncWriter(os.Stderr))
-	if config.logJSON {
+	if app.logJSON {
 		logger = log.NewJSONLogger(log.NewSyncWriter(os.Stderr))
 	}
 
-	logger = level.NewFilter(logger, levelFilter[config.logLevel])
+	logger = level.NewFilter(logger, levelFilter[app.logLevel])
 	logger = log.With(logger,
 		"ts", log.DefaultTimestampUTC,
 		"caller", log.DefaultCaller,
 	)
 
-	var tmpl *template.Template
-	{
-		funcs := template.DefaultFuncs
-		funcs["since"] = func(t time.Time) string {
-			return durafmt.Parse(time.Since(t)).String()
+	if cmd == check.FullCommand() {
+		if err := checkTemplates(os.Stdout, app.alertmanager, checkConfig.files, checkConfig.data); err != nil {
+			level.Error(logger).Log("msg", "failed to check templates", "err", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	if cmd == configCheck.FullCommand() {
+		if app.configFile == "" {
+			level.Error(logger).Log("msg", "pass the configuration file to check with --config.file")
+			os.Exit(2)
 		}
-		funcs["duration"] = func(start time.Time, end time.Time) string {
-			return durafmt.Parse(end.Sub(start)).String()
+		if err := checkConfigFile(os.Stdout, app.configFile); err != nil {
+			level.Error(logger).Log("msg", "failed to check configuration file", "err", err)
+			os.Exit(1)
 		}
+		os.Exit(0)
+	}
 
-		template.DefaultFuncs = funcs
+	cfg, err := loadConfig(app.configFile, flags, set)
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to load configuration", "err", err)
+		os.Exit(1)
+	}
+	if err := requireConfig(cfg); err != nil {
+		level.Error(logger).Log("msg", "invalid configuration", "err", err)
+		os.Exit(2)
+	}
+	alertmanagerURL := cfg.Alertmanagers[0].URL.URL
+
+	token, err := cfg.Telegram.ReadToken()
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to read telegram token", "err", err)
+		os.Exit(1)
+	}
+
+	// reloadMtx guards cfg, which is replaced when the configuration file is reloaded
+	var reloadMtx sync.RWMutex
+
+	currentConfig := func() config.Config {
+		reloadMtx.RLock()
+		defer reloadMtx.RUnlock()
+		return cfg
+	}
 
-		tmpl, err = template.FromGlobs(config.templatesPaths...)
+	var tmpl *telegram.Templates
+	{
+		tmpl, err = telegram.NewTemplates(alertmanagerURL, cfg.Templates.Paths...)
 		if err != nil {
 			level.Error(logger).Log("msg", "failed to parse templates", "err", err)
 			os.Exit(1)
 		}
-		tmpl.ExternalURL = config.alertmanager
 	}
 
 	var kvStore store.Store
 	{
-		switch strings.ToLower(config.store) {
-		case storeBolt:
-			kvStore, err = boltdb.New([]string{config.boltPath}, &store.Config{Bucket: "alertmanager"})
+		switch strings.ToLower(cfg.Store.Type) {
+		case config.StoreBolt:
+			kvStore, err = boltdb.New([]string{cfg.Store.Bolt.Path}, &store.Config{Bucket: "alertmanager"})
 			if err != nil {
 				level.Error(logger).Log("msg", "failed to create bolt store backend", "err", err)
 				os.Exit(1)
 			}
-		case storeConsul:
-			kvStore, err = consul.New([]string{config.consul.String()}, nil)
+		case config.StoreConsul:
+			kvStore, err = consul.New([]string{cfg.Store.Consul.URL.String()}, nil)
 			if err != nil {
 				level.Error(logger).Log("msg", "failed to create consul store backend", "err", err)
 				os.Exit(1)
@@ -186,11 +317,27 @@ func main() {
 	}
 	defer kvStore.Close()
 
+	// reg has all metrics served on /metrics
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+	)
+
+	buildInfo := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "alertmanagerbot",
+		Name:      "build_info",
+		Help:      "A metric with a constant '1' value labeled by version, revision and goversion the bot was built from",
+	}, []string{"version", "revision", "goversion"})
+	buildInfo.WithLabelValues(Version, Revision, GoVersion).Set(1)
+	reg.MustRegister(buildInfo)
+
 	ctx, cancel := context.WithCancel(context.Background())
 
 	// TODO Needs fan out for multiple bots
-	webhooks := make(chan notify.WebhookMessage, 32)
+	webhooks := make(chan alertmanager.Webhook, 32)
 
+	var bot *telegram.Bot
 	var g run.Group
 	{
 		tlogger := log.With(logger, "component", "telegram")
@@ -201,16 +348,54 @@ func main() {
 			os.Exit(1)
 		}
 
-		bot, err := telegram.NewBot(
-			chats, config.telegramToken, config.telegramAdmins[0],
+		routes, err := telegram.NewRouteStore(kvStore)
+		if err != nil {
+			level.Error(logger).Log("msg", "failed to create route store", "err", err)
+			os.Exit(1)
+		}
+
+		pins, err := telegram.NewPinStore(kvStore)
+		if err != nil {
+			level.Error(logger).Log("msg", "failed to create pin store", "err", err)
+			os.Exit(1)
+		}
+
+		// The credentials are read for every request, so their files can change while running
+		alertmanagerTransport, err := alertmanager.NewMetricsTransport(reg, &alertmanager.CredentialsTransport{
+			Credentials: func() (alertmanager.Credentials, error) {
+				return currentConfig().Alertmanagers[0].Credentials()
+			},
+		})
+		if err != nil {
+			level.Error(logger).Log("msg", "failed to register alertmanager metrics", "err", err)
+			os.Exit(1)
+		}
+		alertmanagerClient := &http.Client{Transport: alertmanagerTransport}
+
+		opts := []telegram.BotOption{
 			telegram.WithLogger(tlogger),
-			telegram.WithAddr(config.listenAddr),
-			telegram.WithAlertmanager(config.alertmanager),
+			telegram.WithRegisterer(reg),
+			telegram.WithAddr(cfg.ListenAddr),
+			telegram.WithAlertmanager(alertmanagerURL),
+			telegram.WithAlertmanagerClient(alertmanagerClient),
 			telegram.WithTemplates(tmpl),
+			telegram.WithRouteStore(routes),
+			telegram.WithPinStore(pins),
+			telegram.WithStaticRoutes(staticRoutes(cfg)...),
+			telegram.WithMaxMessages(cfg.Telegram.MaxMessages),
+			telegram.WithWorkers(cfg.Telegram.Workers),
+			telegram.WithQueueSize(cfg.Telegram.QueueSize),
+			telegram.WithAlertsPageSize(cfg.Telegram.AlertsPageSize),
+			telegram.WithAttachments(cfg.Telegram.AttachmentFormat, cfg.Telegram.AttachmentSize),
 			telegram.WithRevision(Revision),
 			telegram.WithStartTime(StartTime),
-			telegram.WithExtraAdmins(config.telegramAdmins[1:]...),
-		)
+			telegram.WithExtraAdmins(cfg.Telegram.Admins[1:]...),
+		}
+		if u := cfg.Telegram.Webhook.URL.URL; u != nil {
+			opts = append(opts, telegram.WithUpdatesWebhook(u, cfg.Telegram.Webhook.SecretToken))
+		}
+
+		bot, err = telegram.NewBot(chats, token, cfg.Telegram.Admins[0], opts...)
 		if err != nil {
 			level.Error(tlogger).Log("msg", "failed to create bot", "err", err)
 			os.Exit(2)
@@ -231,42 +416,255 @@ func main() {
 			cancel()
 		})
 	}
-	{
-		wlogger := log.With(logger, "component", "webserver")
 
-		// TODO: Use Heptio's healthcheck library
-		handleHealth := func(w http.ResponseWriter, r *http.Request) {
-			w.WriteHeader(http.StatusOK)
+	templatesReloads := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "alertmanagerbot",
+		Name:      "templates_reloads_total",
+		Help:      "Number of template reloads by result",
+	}, []string{"result"})
+	templatesReloadSuccessful := prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "alertmanagerbot",
+		Name:      "templates_last_reload_successful",
+		Help:      "Whether the last template reload was successful",
+	})
+	templatesReloadSuccessful.Set(1)
+
+	configReloads := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "alertmanagerbot",
+		Name:      "config_reloads_total",
+		Help:      "Number of configuration file reloads by result",
+	}, []string{"result"})
+	configReloadSuccessful := prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "alertmanagerbot",
+		Name:      "config_last_reload_successful",
+		Help:      "Whether the last configuration file reload was successful",
+	})
+	configReloadSuccessful.Set(1)
+
+	reg.MustRegister(templatesReloads, templatesReloadSuccessful, configReloads, configReloadSuccessful)
+
+	// reloadConfig loads the configuration file again and applies the parts that can change while running,
+	// the admins, routes and credentials. The templates are reloaded by reloadTemplates.
+	reloadConfig := func() error {
+		if app.configFile == "" {
+			return nil
 		}
 
+		c, err := loadConfig(app.configFile, flags, set)
+		if err == nil {
+			err = requireConfig(c)
+		}
+		if err != nil {
+			configReloads.WithLabelValues("failure").Inc()
+			configReloadSuccessful.Set(0)
+			level.Error(logger).Log("msg", "failed to reload configuration file, keeping the previous one", "err", err)
+			return err
+		}
+
+		reloadMtx.Lock()
+		cfg = c
+		reloadMtx.Unlock()
+
+		bot.SetAdmins(c.Telegram.Admins...)
+		bot.SetStaticRoutes(staticRoutes(c)...)
+
+		configReloads.WithLabelValues("success").Inc()
+		configReloadSuccessful.Set(1)
+		level.Info(logger).Log("msg", "reloaded configuration file", "file", app.configFile)
+		return nil
+	}
+
+	var reloadTemplatesMtx sync.Mutex
+	reloadTemplates := func() error {
+		reloadTemplatesMtx.Lock()
+		defer reloadTemplatesMtx.Unlock()
+
+		tmpl, err := telegram.NewTemplates(alertmanagerURL, currentConfig().Templates.Paths...)
+		if err != nil {
+			templatesReloads.WithLabelValues("failure").Inc()
+			templatesReloadSuccessful.Set(0)
+			level.Error(logger).Log("msg", "failed to reload templates, keeping the previous ones", "err", err)
+			return err
+		}
+
+		bot.SetTemplates(tmpl)
+
+		templatesReloads.WithLabelValues("success").Inc()
+		templatesReloadSuccessful.Set(1)
+		level.Info(logger).Log("msg", "reloaded templates", "templates", strings.Join(tmpl.Names(), ","))
+		return nil
+	}
+
+	{
+		wlogger := log.With(logger, "component", "webserver")
+
 		webhooksCounter := prometheus.NewCounter(prometheus.CounterOpts{
 			Namespace: "alertmanagerbot",
 			Name:      "webhooks_total",
 			Help:      "Number of webhooks received by this bot",
 		})
 
-		prometheus.MustRegister(webhooksCounter)
+		reg.MustRegister(webhooksCounter)
+
+		handleWebhook := alertmanager.RequireWebhookAuth(wlogger,
+			func() (alertmanager.Credentials, error) { return currentConfig().Webhook.Credentials() },
+			alertmanager.HandleWebhook(wlogger, webhooksCounter, webhooks),
+		)
+
+		handleReload := func(w http.ResponseWriter, r *http.Request) {
+			if !app.enableLifecycle {
+				http.Error(w, "reloading is not enabled, pass --web.enable-lifecycle", http.StatusForbidden)
+				return
+			}
+			if r.Method != http.MethodPost {
+				http.Error(w, "only POST requests reload the configuration and templates", http.StatusMethodNotAllowed)
+				return
+			}
+			if err := reloadConfig(); err != nil {
+				http.Error(w, fmt.Sprintf("failed to reload configuration file: %v", err), http.StatusInternalServerError)
+				return
+			}
+			if err := reloadTemplates(); err != nil {
+				http.Error(w, fmt.Sprintf("failed to reload templates: %v", err), http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}
 
 		m := http.NewServeMux()
-		m.HandleFunc("/", alertmanager.HandleWebhook(wlogger, webhooksCounter, webhooks))
-		m.Handle("/metrics", promhttp.Handler())
-		m.HandleFunc("/health", handleHealth)
-		m.HandleFunc("/healthz", handleHealth)
+		m.HandleFunc("/", handleWebhook)
+		m.HandleFunc(alertmanager.WebhookRoutePrefix, handleWebhook)
+		m.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+		m.HandleFunc("/health", handleHealthy)
+		m.HandleFunc("/healthz", handleHealthy)
+		m.HandleFunc("/-/healthy", handleHealthy)
+		m.HandleFunc("/-/ready", handleReady(func(ctx context.Context) map[string]error {
+			checks := bot.Checks(ctx)
+			checks["webhooks"] = nil
+			if len(webhooks) == cap(webhooks) {
+				checks["webhooks"] = fmt.Errorf("the queue of %d webhooks is full", cap(webhooks))
+			}
+			return checks
+		}))
+		m.HandleFunc("/-/reload", handleReload)
+		if cfg.Telegram.Webhook.URL.URL != nil {
+			// Telegram sends updates with a secret token instead of the webhook credentials
+			m.HandleFunc(cfg.Telegram.Webhook.Path, bot.HandleUpdate)
+		}
 
 		s := http.Server{
-			Addr:    config.listenAddr,
+			Addr:    cfg.ListenAddr,
 			Handler: m,
 		}
 
 		g.Add(func() error {
-			level.Info(wlogger).Log("msg", "starting webserver", "addr", config.listenAddr)
+			level.Info(wlogger).Log("msg", "starting webserver", "addr", s.Addr)
 			return s.ListenAndServe()
 		}, func(err error) {
 			s.Shutdown(context.Background())
 		})
 	}
 	{
-		sig := make(chan os.Signal)
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		done := make(chan struct{})
+
+		g.Add(func() error {
+			for {
+				select {
+				case <-hup:
+					if reloadConfig() == nil {
+						reloadTemplates()
+					}
+				case <-done:
+					return nil
+				}
+			}
+		}, func(err error) {
+			signal.Stop(hup)
+			close(done)
+		})
+	}
+	if cfg.Templates.Watch {
+		watcher, err := fsnotify.NewWatcher()
+		if err != nil {
+			level.Error(logger).Log("msg", "failed to watch templates", "err", err)
+			os.Exit(1)
+		}
+
+		// Editors often replace files instead of writing them and new files can match the paths' globs,
+		// so the directories are watched and their events filtered by the templates' paths.
+		paths := cfg.Templates.Paths
+		watched := map[string]bool{}
+		for _, p := range paths {
+			dir := filepath.Dir(filepath.Clean(p))
+			if watched[dir] {
+				continue
+			}
+			watched[dir] = true
+			if err := watcher.Add(dir); err != nil {
+				level.Error(logger).Log("msg", "failed to watch templates", "path", p, "err", err)
+				os.Exit(1)
+			}
+		}
+
+		g.Add(func() error {
+			// Changes come in bursts, reload once they settled
+			var reload <-chan time.Time
+			for {
+				select {
+				case e, ok := <-watcher.Events:
+					if !ok {
+						return nil
+					}
+					if templateChanged(paths, e.Name) {
+						reload = time.After(time.Second)
+					}
+				case err, ok := <-watcher.Errors:
+					if !ok {
+						return nil
+					}
+					level.Warn(logger).Log("msg", "failed to watch templates", "err", err)
+				case <-reload:
+					reload = nil
+					reloadTemplates()
+				}
+			}
+		}, func(err error) {
+			watcher.Close()
+		})
+	}
+	if tokenFile := cfg.Telegram.TokenFile; tokenFile != "" {
+		// Switch to the token of the file once it changes, like when a revoked token is replaced,
+		// keeping the queued notifications and the state of the running bot.
+		ticker := time.NewTicker(30 * time.Second)
+		done := make(chan struct{})
+
+		g.Add(func() error {
+			for {
+				select {
+				case <-ticker.C:
+					t, err := config.TelegramConfig{TokenFile: tokenFile}.ReadToken()
+					if err != nil {
+						level.Warn(logger).Log("msg", "failed to read telegram token", "err", err)
+						continue
+					}
+					if t != "" && t != token {
+						bot.SetToken(t)
+						token = t
+						level.Info(logger).Log("msg", "telegram token changed, switched to the new token", "file", tokenFile)
+					}
+				case <-done:
+					return nil
+				}
+			}
+		}, func(err error) {
+			ticker.Stop()
+			close(done)
+		})
+	}
+	{
+		sig := make(chan os.Signal, 1)
 		signal.Notify(sig, os.Interrupt, os.Kill)
 
 		g.Add(func() error {
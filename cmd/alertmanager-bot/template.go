@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/telegram"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/template"
+)
+
+// kubernetesDataDir is the symlink Kubernetes swaps to update the files of mounted ConfigMaps and Secrets
+const kubernetesDataDir = "..data"
+
+// templateChanged returns true if the changed file matches one of the templates' paths, which can be globs,
+// or is the ..data symlink of a Kubernetes volume in one of their directories.
+func templateChanged(paths []string, name string) bool {
+	name = filepath.Clean(name)
+	for _, p := range paths {
+		p = filepath.Clean(p)
+		if filepath.Dir(p) != filepath.Dir(name) {
+			continue
+		}
+		if filepath.Base(name) == kubernetesDataDir {
+			return true
+		}
+		if ok, _ := filepath.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// checkTemplates renders every notification template defined in files
+// with the webhook read from dataPath or the sample alerts and writes the results to w.
+func checkTemplates(w io.Writer, externalURL *url.URL, files []string, dataPath string) error {
+	tmpl, err := telegram.NewTemplates(externalURL, files...)
+	if err != nil {
+		return err
+	}
+
+	data := tmpl.SampleData()
+	if dataPath != "" {
+		f, err := os.Open(dataPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		var webhook notify.WebhookMessage
+		if err := json.NewDecoder(f).Decode(&webhook); err != nil {
+			return fmt.Errorf("failed to decode webhook %s: %v", dataPath, err)
+		}
+		if webhook.Data == nil {
+			webhook.Data = &template.Data{}
+		}
+		data = webhook.Data
+	}
+
+	for _, name := range tmpl.Names() {
+		out, err := tmpl.Notification(name, telegram.DefaultLanguage, data)
+		if err != nil {
+			return fmt.Errorf("template telegram.%s is invalid: %v", name, err)
+		}
+		fmt.Fprintf(w, "==> telegram.%s (%s)\n%s\n", name, out.ParseMode, out.Text)
+	}
+
+	return nil
+}
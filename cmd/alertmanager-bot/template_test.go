@@ -0,0 +1,21 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplateChanged(t *testing.T) {
+	paths := []string{"/templates/*.tmpl", "/etc/bot/custom.tmpl"}
+
+	assert.True(t, templateChanged(paths, "/templates/default.tmpl"))
+	assert.True(t, templateChanged(paths, "/templates/new.tmpl"))
+	assert.True(t, templateChanged(paths, "/etc/bot/custom.tmpl"))
+	assert.True(t, templateChanged(paths, "/templates/..data"))
+	assert.True(t, templateChanged(paths, "/etc/bot/..data"))
+
+	assert.False(t, templateChanged(paths, "/templates/default.tmpl.swp"))
+	assert.False(t, templateChanged(paths, "/etc/bot/other.tmpl"))
+	assert.False(t, templateChanged(paths, "/other/..data"))
+}
@@ -15,6 +15,8 @@ function(params) {
       json: false,
     },
     storage: error 'please provide the storage configuration',
+    // The secret with the telegram token is mounted as files, which are read again when they change
+    secretsPath: '/etc/alertmanager-bot/secrets',
 
     // Set defaults for Kubernetes from the defaults above
     metadata: {
@@ -76,6 +78,10 @@ function(params) {
                 '--log.level=%s' % bot.log.level,
               ] + (
                 if bot.log.json then ['--log.json'] else []
+              ) + (
+                if std.objectHas(bot, 'telegram') then [
+                  '--telegram.token-file=%s/token' % bot.secretsPath,
+                ] else []
               ) + (
                 if std.objectHas(bot.storage, 'bolt') then [
                   '--store=bolt',
@@ -95,13 +101,6 @@ function(params) {
                     key: 'admin',
                   } },
                 },
-                {
-                  name: 'TELEGRAM_TOKEN',
-                  valueFrom: { secretKeyRef: {
-                    name: bot.metadata.name,
-                    key: 'token',
-                  } },
-                },
               ] else [],
               ports: [
                 { name: name, containerPort: bot.ports[name] }
@@ -110,14 +109,24 @@ function(params) {
               resources: bot.resources,
               volumeMounts: [
                 { mountPath: '/data', name: 'data' },
-              ],
+              ] + (
+                if std.objectHas(bot, 'telegram') then [
+                  { mountPath: bot.secretsPath, name: 'secrets', readOnly: true },
+                ] else []
+              ),
             },
           ],
-          volumes: if std.objectHas(bot, 'pvc') then [
-            { name: 'data', persistentVolumeClaim: { claimName: 'data' } },
-          ] else [
-            { name: 'data', emptyDir: {} },
-          ],
+          volumes: (
+            if std.objectHas(bot, 'pvc') then [
+              { name: 'data', persistentVolumeClaim: { claimName: 'data' } },
+            ] else [
+              { name: 'data', emptyDir: {} },
+            ]
+          ) + (
+            if std.objectHas(bot, 'telegram') then [
+              { name: 'secrets', secret: { secretName: bot.metadata.name } },
+            ] else []
+          ),
         },
       },
       volumeClaimTemplates: if std.objectHas(bot, 'pvc') then [
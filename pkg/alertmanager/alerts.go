@@ -2,20 +2,107 @@ package alertmanager
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
 
 	"github.com/go-kit/kit/log"
 	"github.com/prometheus/alertmanager/types"
 )
 
+// matcherRegexp parses matchers like severity=critical, job!="node" or instance=~"db-.*"
+var matcherRegexp = regexp.MustCompile(`^([a-zA-Z_][a-zA-Z0-9_]*)(=~|!~|!=|=)(.*)$`)
+
 type alertResponse struct {
 	Status string         `json:"status"`
 	Alerts []*types.Alert `json:"data,omitempty"`
 }
 
+// ParseMatchers normalizes matchers like severity=critical or instance=~"db-.*"
+// into the quoted form understood by the Alertmanager API, like instance=~"db-.*".
+func ParseMatchers(matchers []string) ([]string, error) {
+	parsed := make([]string, 0, len(matchers))
+	for _, m := range matchers {
+		parts := matcherRegexp.FindStringSubmatch(m)
+		if parts == nil {
+			return nil, fmt.Errorf("invalid matcher %q, use label=value, label!=value, label=~regex or label!~regex", m)
+		}
+
+		value := parts[3]
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+
+		parsed = append(parsed, parts[1]+parts[2]+strconv.Quote(value))
+	}
+
+	return parsed, nil
+}
+
+// AlertsFilter turns matchers like severity=critical or instance=~"db-.*" into
+// a filter understood by the Alertmanager API, like {severity="critical",instance=~"db-.*"}.
+// No matchers return an empty filter matching all alerts.
+func AlertsFilter(matchers []string) (string, error) {
+	if len(matchers) == 0 {
+		return "", nil
+	}
+
+	parsed, err := ParseMatchers(matchers)
+	if err != nil {
+		return "", err
+	}
+
+	return "{" + strings.Join(parsed, ",") + "}", nil
+}
+
+// MatchLabels returns true if the labels match all matchers, like severity!=critical or instance=~"db-.*".
+// Regular expressions have to match the whole value and missing labels have an empty value, like in Prometheus.
+func MatchLabels(matchers []string, labels map[string]string) (bool, error) {
+	parsed, err := ParseMatchers(matchers)
+	if err != nil {
+		return false, err
+	}
+
+	for _, m := range parsed {
+		parts := matcherRegexp.FindStringSubmatch(m)
+		name, op := parts[1], parts[2]
+		value, err := strconv.Unquote(parts[3])
+		if err != nil {
+			return false, err
+		}
+
+		var matches bool
+		switch op {
+		case "=", "!=":
+			matches = labels[name] == value
+		default:
+			re, err := regexp.Compile("^(?:" + value + ")$")
+			if err != nil {
+				return false, fmt.Errorf("invalid regex in matcher %s: %v", m, err)
+			}
+			matches = re.MatchString(labels[name])
+		}
+
+		if matches != (op == "=" || op == "=~") {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
 // ListAlerts returns a slice of Alert and an error.
-func ListAlerts(logger log.Logger, alertmanagerURL string) ([]*types.Alert, error) {
-	resp, err := httpRetry(logger, http.MethodGet, alertmanagerURL+"/api/v1/alerts")
+// A non-empty filter, as returned by AlertsFilter, only returns matching alerts.
+func ListAlerts(logger log.Logger, client *http.Client, alertmanagerURL string, filter string) ([]*types.Alert, error) {
+	u := alertmanagerURL + "/api/v1/alerts"
+	if filter != "" {
+		u = u + "?" + url.Values{"filter": []string{filter}}.Encode()
+	}
+
+	resp, err := httpRetry(logger, client, http.MethodGet, u)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,42 @@
+package alertmanager
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAlertsFilter(t *testing.T) {
+	filter, err := AlertsFilter(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "", filter)
+
+	filter, err = AlertsFilter([]string{"severity=critical", `job!="node"`, `instance=~db-.*`, "env!~dev|test"})
+	assert.NoError(t, err)
+	assert.Equal(t, `{severity="critical",job!="node",instance=~"db-.*",env!~"dev|test"}`, filter)
+
+	_, err = AlertsFilter([]string{"critical"})
+	assert.Error(t, err)
+}
+
+func TestMatchLabels(t *testing.T) {
+	labels := map[string]string{"severity": "warning", "instance": "db-1"}
+
+	for matchers, expected := range map[string]bool{
+		"severity!=critical":                 true,
+		"severity=critical":                  false,
+		`instance=~"db-.*"`:                  true,
+		"instance=~db":                       false,
+		"instance!~web-.*":                   true,
+		"env=":                               true,
+		"severity!=critical instance=~web.*": false,
+	} {
+		matches, err := MatchLabels(strings.Fields(matchers), labels)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, matches, matchers)
+	}
+
+	_, err := MatchLabels([]string{"instance=~("}, labels)
+	assert.Error(t, err)
+}
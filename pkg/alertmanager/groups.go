@@ -0,0 +1,42 @@
+package alertmanager
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// AlertGroup is a group of alerts as grouped by Alertmanager's route for a receiver
+type AlertGroup struct {
+	Labels   map[string]string `json:"labels"`
+	Receiver struct {
+		Name string `json:"name"`
+	} `json:"receiver"`
+	Alerts []*types.Alert `json:"alerts"`
+}
+
+// ListAlertGroups returns a slice of AlertGroup and an error.
+// Only alerts matching all matchers, as returned by ParseMatchers, are part of the groups.
+func ListAlertGroups(logger log.Logger, client *http.Client, alertmanagerURL string, matchers []string) ([]AlertGroup, error) {
+	u := alertmanagerURL + "/api/v2/alerts/groups"
+	if len(matchers) > 0 {
+		u = u + "?" + url.Values{"filter": matchers}.Encode()
+	}
+
+	resp, err := httpRetry(logger, client, http.MethodGet, u)
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []AlertGroup
+	dec := json.NewDecoder(resp.Body)
+	defer resp.Body.Close()
+	if err := dec.Decode(&groups); err != nil {
+		return nil, err
+	}
+
+	return groups, nil
+}
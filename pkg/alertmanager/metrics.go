@@ -0,0 +1,74 @@
+package alertmanager
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// MetricsTransport observes the requests to the Alertmanager API by endpoint, every retry is a request of its own.
+type MetricsTransport struct {
+	requestDuration *prometheus.HistogramVec
+	requestErrors   *prometheus.CounterVec
+	// base sends the requests, http.DefaultTransport if nil
+	base http.RoundTripper
+}
+
+// NewMetricsTransport returns a transport sending requests with base and its metrics registered with r
+func NewMetricsTransport(r prometheus.Registerer, base http.RoundTripper) (*MetricsTransport, error) {
+	t := &MetricsTransport{
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "alertmanagerbot",
+			Subsystem: "alertmanager",
+			Name:      "request_duration_seconds",
+			Help:      "Duration of requests to the Alertmanager API by endpoint, every retry is observed",
+			Buckets:   []float64{.01, .025, .05, .1, .25, .5, 1, 2},
+		}, []string{"endpoint"}),
+		requestErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "alertmanagerbot",
+			Subsystem: "alertmanager",
+			Name:      "request_errors_total",
+			Help:      "Number of failed requests to the Alertmanager API by endpoint, including the ones retried",
+		}, []string{"endpoint"}),
+		base: base,
+	}
+
+	for _, c := range []prometheus.Collector{t.requestDuration, t.requestErrors} {
+		if err := r.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// RoundTrip sends the request and observes its duration and whether it failed
+func (t *MetricsTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	ep := endpoint(r.URL.String())
+	start := time.Now()
+	resp, err := base.RoundTrip(r)
+	t.requestDuration.WithLabelValues(ep).Observe(time.Since(start).Seconds())
+	if err != nil || resp.StatusCode >= http.StatusBadRequest {
+		t.requestErrors.WithLabelValues(ep).Inc()
+	}
+	return resp, err
+}
+
+// endpoint returns the API path of the URL, like /api/v1/alerts for http://alertmanager/prefix/api/v1/alerts?silenced=false
+func endpoint(rawurl string) string {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return "unknown"
+	}
+	if i := strings.Index(u.Path, "/api/"); i >= 0 {
+		return u.Path[i:]
+	}
+	return u.Path
+}
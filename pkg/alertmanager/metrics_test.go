@@ -0,0 +1,44 @@
+package alertmanager
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEndpoint(t *testing.T) {
+	assert.Equal(t, "/api/v1/alerts", endpoint("http://localhost:9093/api/v1/alerts?silenced=false"))
+	assert.Equal(t, "/api/v1/status", endpoint("https://example.com/alertmanager/api/v1/status"))
+	assert.Equal(t, "/", endpoint("http://localhost:9093/"))
+}
+
+func TestMetricsTransport(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1/status" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer s.Close()
+
+	reg := prometheus.NewRegistry()
+	transport, err := NewMetricsTransport(reg, nil)
+	assert.NoError(t, err)
+	client := &http.Client{Transport: transport}
+
+	for _, path := range []string{"/api/v1/alerts", "/api/v1/status", "/api/v1/status"} {
+		resp, err := client.Get(s.URL + path)
+		assert.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	assert.Equal(t, float64(0), testutil.ToFloat64(transport.requestErrors.WithLabelValues("/api/v1/alerts")))
+	assert.Equal(t, float64(2), testutil.ToFloat64(transport.requestErrors.WithLabelValues("/api/v1/status")))
+
+	// every registry gets collectors of its own
+	_, err = NewMetricsTransport(prometheus.NewRegistry(), nil)
+	assert.NoError(t, err)
+}
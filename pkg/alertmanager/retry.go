@@ -19,11 +19,11 @@ func httpBackoff() *backoff.ExponentialBackOff {
 	return b
 }
 
-func httpRetry(logger log.Logger, method string, url string) (*http.Response, error) {
+func httpRetry(logger log.Logger, client *http.Client, method string, url string) (*http.Response, error) {
 	var resp *http.Response
 	var err error
 
-	fn := func() error {
+	request := func() error {
 		req, err := http.NewRequest(method, url, nil)
 		if err != nil {
 			return err
@@ -33,7 +33,7 @@ func httpRetry(logger log.Logger, method string, url string) (*http.Response, er
 		defer cancel()
 		req = req.WithContext(ctx)
 
-		resp, err = http.DefaultClient.Do(req)
+		resp, err = client.Do(req)
 		if err != nil {
 			return err
 		}
@@ -61,7 +61,7 @@ func httpRetry(logger log.Logger, method string, url string) (*http.Response, er
 		)
 	}
 
-	if err := backoff.RetryNotify(fn, httpBackoff(), notify); err != nil {
+	if err := backoff.RetryNotify(request, httpBackoff(), notify); err != nil {
 		return nil, err
 	}
 
@@ -1,7 +1,9 @@
 package alertmanager
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"time"
 
@@ -25,10 +27,10 @@ type StatusResponse struct {
 }
 
 // Status returns a StatusResponse or an error.
-func Status(logger log.Logger, alertmanagerURL string) (StatusResponse, error) {
+func Status(logger log.Logger, client *http.Client, alertmanagerURL string) (StatusResponse, error) {
 	var statusResponse StatusResponse
 
-	resp, err := httpRetry(logger, http.MethodGet, alertmanagerURL+"/api/v1/status")
+	resp, err := httpRetry(logger, client, http.MethodGet, alertmanagerURL+"/api/v1/status")
 	if err != nil {
 		return statusResponse, err
 	}
@@ -41,3 +43,22 @@ func Status(logger log.Logger, alertmanagerURL string) (StatusResponse, error) {
 
 	return statusResponse, nil
 }
+
+// Healthy returns an error if Alertmanager doesn't answer its health check, it isn't retried
+func Healthy(ctx context.Context, client *http.Client, alertmanagerURL string) error {
+	req, err := http.NewRequest(http.MethodGet, alertmanagerURL+"/-/healthy", nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status code is %d not 200", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package alertmanager
+
+import (
+	"net/http"
+)
+
+// CredentialsTransport sends requests to Alertmanager with the credentials returned by Credentials,
+// which is called for every request so the credentials can change while running.
+type CredentialsTransport struct {
+	Credentials func() (Credentials, error)
+	// Base sends the requests, http.DefaultTransport if nil
+	Base http.RoundTripper
+}
+
+// RoundTrip sends the request with the credentials
+func (t *CredentialsTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	creds, err := t.Credentials()
+	if err != nil {
+		return nil, err
+	}
+	if creds.BearerToken == "" && creds.Username == "" {
+		return base.RoundTrip(r)
+	}
+
+	// RoundTrippers must not modify the request
+	r = r.Clone(r.Context())
+	if creds.BearerToken != "" {
+		r.Header.Set("Authorization", "Bearer "+creds.BearerToken)
+	}
+	if creds.Username != "" {
+		r.SetBasicAuth(creds.Username, creds.Password)
+	}
+	return base.RoundTrip(r)
+}
@@ -0,0 +1,42 @@
+package alertmanager
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCredentialsTransport(t *testing.T) {
+	var authorization string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+	}))
+	defer s.Close()
+
+	var creds Credentials
+	var credsErr error
+	client := &http.Client{Transport: &CredentialsTransport{
+		Credentials: func() (Credentials, error) { return creds, credsErr },
+	}}
+
+	_, err := client.Get(s.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, "", authorization)
+
+	creds = Credentials{BearerToken: "secret"}
+	_, err = client.Get(s.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer secret", authorization)
+
+	creds = Credentials{Username: "bot", Password: "secret"}
+	_, err = client.Get(s.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, "Basic Ym90OnNlY3JldA==", authorization)
+
+	credsErr = errors.New("secret file is missing")
+	_, err = client.Get(s.URL)
+	assert.Error(t, err)
+}
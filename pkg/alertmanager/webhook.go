@@ -1,8 +1,10 @@
 package alertmanager
 
 import (
+	"crypto/subtle"
 	"encoding/json"
 	"net/http"
+	"strings"
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
@@ -10,14 +12,36 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 )
 
+// WebhookRoutePrefix is the path prefix for webhooks addressed to a specific route,
+// like /webhook/team-db or /webhook/chat/-100123.
+const WebhookRoutePrefix = "/webhook/"
+
+// Webhook is a message received from Alertmanager together with the route it was sent to
+type Webhook struct {
+	notify.WebhookMessage
+
+	// Route is the path below WebhookRoutePrefix the webhook was sent to.
+	// It's empty for webhooks that should be sent to all subscribers.
+	Route string
+}
+
 // HandleWebhook returns a HandlerFunc that forwards webhooks to all bots via a channel
-func HandleWebhook(logger log.Logger, counter prometheus.Counter, webhooks chan<- notify.WebhookMessage) http.HandlerFunc {
+func HandleWebhook(logger log.Logger, counter prometheus.Counter, webhooks chan<- Webhook) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			w.WriteHeader(http.StatusMethodNotAllowed)
 			return
 		}
 
+		var route string
+		if strings.HasPrefix(r.URL.Path, WebhookRoutePrefix) {
+			route = strings.Trim(strings.TrimPrefix(r.URL.Path, WebhookRoutePrefix), "/")
+			if route == "" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+		}
+
 		if r.Body == nil {
 			w.WriteHeader(http.StatusBadRequest)
 			return
@@ -39,9 +63,58 @@ func HandleWebhook(logger log.Logger, counter prometheus.Counter, webhooks chan<
 		level.Debug(logger).Log(
 			"msg", "received webhook",
 			"alerts", len(webhook.Alerts),
+			"route", route,
 		)
 
-		webhooks <- webhook
+		webhooks <- Webhook{WebhookMessage: webhook, Route: route}
 		counter.Inc()
 	}
 }
+
+// Credentials are a bearer token and basic auth, like the ones Alertmanager has to send webhooks with,
+// configured in its webhook_config's http_config. Empty credentials aren't checked or sent.
+type Credentials struct {
+	BearerToken string
+	Username    string
+	Password    string
+}
+
+// authorized returns true if the request carries the credentials
+func (a Credentials) authorized(r *http.Request) bool {
+	if a.BearerToken != "" {
+		header := r.Header.Get("Authorization")
+		if !strings.HasPrefix(header, "Bearer ") ||
+			subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(header, "Bearer ")), []byte(a.BearerToken)) != 1 {
+			return false
+		}
+	}
+	if a.Username != "" {
+		username, password, ok := r.BasicAuth()
+		if !ok ||
+			subtle.ConstantTimeCompare([]byte(username), []byte(a.Username)) != 1 ||
+			subtle.ConstantTimeCompare([]byte(password), []byte(a.Password)) != 1 {
+			return false
+		}
+	}
+	return true
+}
+
+// RequireWebhookAuth only passes requests carrying the credentials returned by auth to next,
+// auth is called for every request so the credentials can change while running.
+func RequireWebhookAuth(logger log.Logger, auth func() (Credentials, error), next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		creds, err := auth()
+		if err != nil {
+			level.Error(logger).Log("msg", "failed to read webhook credentials", "err", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if !creds.authorized(r) {
+			level.Warn(logger).Log("msg", "rejected webhook with invalid credentials", "remote", r.RemoteAddr)
+			w.Header().Set("WWW-Authenticate", `Basic realm="alertmanager-bot"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
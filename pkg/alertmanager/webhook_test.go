@@ -20,7 +20,7 @@ const validWebhook = `{"receiver":"telegram","status":"firing","alerts":[{"statu
 func TestHandleWebhook(t *testing.T) {
 	logger := log.NewNopLogger()
 	counter := prometheus.NewCounter(prometheus.CounterOpts{})
-	webhooks := make(chan notify.WebhookMessage, 1)
+	webhooks := make(chan Webhook, 1)
 
 	h := HandleWebhook(logger, counter, webhooks)
 
@@ -89,7 +89,37 @@ func TestHandleWebhook(t *testing.T) {
 					}
 
 					webhook := <-webhooks
-					if !assert.Equal(t, expected, webhook) {
+					if !assert.Equal(t, Webhook{WebhookMessage: expected}, webhook) {
+						return errors.New("")
+					}
+					return nil
+				},
+			},
+		},
+		{
+			name: "EmptyRoute",
+			req: func() *http.Request {
+				body := bytes.NewBufferString(validWebhook)
+				req, _ := http.NewRequest(http.MethodPost, WebhookRoutePrefix, body)
+				return req
+			},
+			checks: []checkFunc{
+				checkStatusCode(http.StatusNotFound),
+			},
+		},
+		{
+			name: "RoutedWebhook",
+			req: func() *http.Request {
+				body := bytes.NewBufferString(validWebhook)
+				req, _ := http.NewRequest(http.MethodPost, WebhookRoutePrefix+"chat/-100123/", body)
+				return req
+			},
+			checks: []checkFunc{
+				checkStatusCode(http.StatusOK),
+
+				func(resp *http.Response) error {
+					webhook := <-webhooks
+					if !assert.Equal(t, "chat/-100123", webhook.Route) {
 						return errors.New("")
 					}
 					return nil
@@ -111,3 +141,34 @@ func TestHandleWebhook(t *testing.T) {
 		})
 	}
 }
+
+func TestRequireWebhookAuth(t *testing.T) {
+	auth := Credentials{}
+	var authErr error
+	h := RequireWebhookAuth(log.NewNopLogger(), func() (Credentials, error) { return auth, authErr }, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	status := func(modify func(r *http.Request)) int {
+		req, _ := http.NewRequest(http.MethodPost, "/", nil)
+		modify(req)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, status(func(r *http.Request) {}))
+
+	auth = Credentials{BearerToken: "secret"}
+	assert.Equal(t, http.StatusUnauthorized, status(func(r *http.Request) {}))
+	assert.Equal(t, http.StatusUnauthorized, status(func(r *http.Request) { r.Header.Set("Authorization", "Bearer wrong") }))
+	assert.Equal(t, http.StatusUnauthorized, status(func(r *http.Request) { r.Header.Set("Authorization", "secret") }))
+	assert.Equal(t, http.StatusOK, status(func(r *http.Request) { r.Header.Set("Authorization", "Bearer secret") }))
+
+	auth = Credentials{Username: "alertmanager", Password: "secret"}
+	assert.Equal(t, http.StatusUnauthorized, status(func(r *http.Request) { r.SetBasicAuth("alertmanager", "wrong") }))
+	assert.Equal(t, http.StatusOK, status(func(r *http.Request) { r.SetBasicAuth("alertmanager", "secret") }))
+
+	authErr = errors.New("secret file is missing")
+	assert.Equal(t, http.StatusInternalServerError, status(func(r *http.Request) { r.SetBasicAuth("alertmanager", "secret") }))
+}
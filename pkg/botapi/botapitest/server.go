@@ -0,0 +1,257 @@
+// Package botapitest is a fake Telegram Bot API server for tests
+package botapitest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/botapi"
+)
+
+// Token is the token of the fake bot
+const Token = "123456:test"
+
+// Me is the fake bot's user returned by getMe
+var Me = botapi.User{ID: 123456, IsBot: true, FirstName: "Alertmanager", Username: "alertmanager_bot"}
+
+// Request is a call of a method, its parameters are either JSON or multipart form values
+type Request struct {
+	Method string
+	Params map[string]interface{}
+	// Files are the contents of uploaded files by their names
+	Files map[string]string
+}
+
+// Param returns the parameter formatted as string, like "-100123" for a chat_id
+func (r Request) Param(name string) string {
+	v, ok := r.Params[name]
+	if !ok {
+		return ""
+	}
+	if s, ok := v.(string); ok {
+		return s
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// HandlerFunc answers a request with a result or an error
+type HandlerFunc func(r Request) (interface{}, *botapi.Error)
+
+// Server is a fake Bot API that records all requests and answers them like Telegram would.
+// Answers can be changed with Handle, updates returned by getUpdates are queued with Update.
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	requests  []Request
+	handlers  map[string]HandlerFunc
+	messageID int
+
+	updates chan botapi.Update
+}
+
+// NewServer starts a fake Bot API, it has to be closed
+func NewServer() *Server {
+	s := &Server{
+		handlers: map[string]HandlerFunc{},
+		updates:  make(chan botapi.Update, 100),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// Client returns a client of the fake bot
+func (s *Server) Client() *botapi.Client {
+	return botapi.New(Token, botapi.WithURL(s.URL))
+}
+
+// Handle answers the method with the handler instead of the default answer
+func (s *Server) Handle(method string, h HandlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[method] = h
+}
+
+// Update queues an update for getUpdates
+func (s *Server) Update(u botapi.Update) {
+	s.updates <- u
+}
+
+// Requests returns the requests of the methods, all requests if no methods are given
+func (s *Server) Requests(methods ...string) []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var requests []Request
+	for _, r := range s.requests {
+		if len(methods) == 0 || contains(methods, r.Method) {
+			requests = append(requests, r)
+		}
+	}
+	return requests
+}
+
+// WaitRequests waits up to a second for n requests of the method and returns them
+func (s *Server) WaitRequests(method string, n int) []Request {
+	deadline := time.Now().Add(time.Second)
+	for {
+		requests := s.Requests(method)
+		if len(requests) >= n || time.Now().After(deadline) {
+			return requests
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/bot"), "/", 2)
+	if len(parts) != 2 || parts[0] != Token {
+		writeResponse(w, nil, &botapi.Error{Code: http.StatusUnauthorized, Description: "Unauthorized"})
+		return
+	}
+
+	req, err := parseRequest(parts[1], r)
+	if err != nil {
+		writeResponse(w, nil, &botapi.Error{Code: http.StatusBadRequest, Description: err.Error()})
+		return
+	}
+
+	if req.Method == "getUpdates" {
+		writeResponse(w, s.getUpdates(r, req), nil)
+		return
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	handler, ok := s.handlers[req.Method]
+	s.mu.Unlock()
+
+	if ok {
+		result, apiErr := handler(req)
+		writeResponse(w, result, apiErr)
+		return
+	}
+	writeResponse(w, s.defaultResult(req), nil)
+}
+
+// getUpdates returns the queued updates, waiting for the first one up to the request's timeout
+func (s *Server) getUpdates(r *http.Request, req Request) []botapi.Update {
+	timeout := time.Duration(0)
+	if t, ok := req.Params["timeout"].(json.Number); ok {
+		seconds, _ := t.Int64()
+		timeout = time.Duration(seconds) * time.Second
+	}
+
+	updates := []botapi.Update{}
+	select {
+	case u := <-s.updates:
+		updates = append(updates, u)
+	case <-time.After(timeout):
+		return updates
+	case <-r.Context().Done():
+		return updates
+	}
+	for {
+		select {
+		case u := <-s.updates:
+			updates = append(updates, u)
+		default:
+			return updates
+		}
+	}
+}
+
+// defaultResult is the result Telegram returns for the request if everything goes well
+func (s *Server) defaultResult(req Request) interface{} {
+	switch req.Method {
+	case "getMe":
+		return Me
+	case "sendMessage", "sendDocument":
+		s.mu.Lock()
+		s.messageID++
+		id := s.messageID
+		s.mu.Unlock()
+
+		var chatID int64
+		fmt.Sscan(req.Param("chat_id"), &chatID)
+		return botapi.Message{
+			ID:   id,
+			From: Me,
+			Date: time.Now().Unix(),
+			Chat: botapi.Chat{ID: chatID},
+			Text: req.Param("text"),
+		}
+	default:
+		return true
+	}
+}
+
+func parseRequest(method string, r *http.Request) (Request, error) {
+	req := Request{Method: method, Params: map[string]interface{}{}, Files: map[string]string{}}
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			return req, err
+		}
+		for name, values := range r.MultipartForm.Value {
+			req.Params[name] = values[0]
+		}
+		for _, headers := range r.MultipartForm.File {
+			f, err := headers[0].Open()
+			if err != nil {
+				return req, err
+			}
+			content, err := ioutil.ReadAll(f)
+			f.Close()
+			if err != nil {
+				return req, err
+			}
+			req.Files[headers[0].Filename] = string(content)
+		}
+		return req, nil
+	}
+
+	dec := json.NewDecoder(r.Body)
+	dec.UseNumber()
+	return req, dec.Decode(&req.Params)
+}
+
+// writeResponse writes the result or the error in the envelope of the Bot API
+func writeResponse(w http.ResponseWriter, result interface{}, apiErr *botapi.Error) {
+	w.Header().Set("Content-Type", "application/json")
+
+	resp := map[string]interface{}{"ok": true, "result": result}
+	if apiErr != nil {
+		w.WriteHeader(apiErr.Code)
+		resp = map[string]interface{}{"ok": false, "error_code": apiErr.Code, "description": apiErr.Description}
+
+		parameters := map[string]interface{}{}
+		if apiErr.RetryAfter > 0 {
+			parameters["retry_after"] = apiErr.RetryAfter
+		}
+		if apiErr.MigrateToChatID != 0 {
+			parameters["migrate_to_chat_id"] = apiErr.MigrateToChatID
+		}
+		if len(parameters) > 0 {
+			resp["parameters"] = parameters
+		}
+	}
+
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,291 @@
+// Package botapi is a client for the methods of the Telegram Bot API the bot uses,
+// see https://core.telegram.org/bots/api.
+package botapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultURL is the URL of Telegram's Bot API server
+const DefaultURL = "https://api.telegram.org"
+
+// requestTimeout limits requests whose context has no deadline
+const requestTimeout = time.Minute
+
+// Client calls the methods of the Bot API as the bot with the token
+type Client struct {
+	url    string
+	client *http.Client
+
+	mu    sync.RWMutex
+	token string
+}
+
+// Option passed to New to change the default client
+type Option func(c *Client)
+
+// WithURL sets the URL of the Bot API server, like a local one
+func WithURL(url string) Option {
+	return func(c *Client) {
+		c.url = strings.TrimSuffix(url, "/")
+	}
+}
+
+// WithHTTPClient sets the client requests are sent with
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) {
+		c.client = client
+	}
+}
+
+// New returns a client for the bot with the token
+func New(token string, opts ...Option) *Client {
+	c := &Client{
+		url:    DefaultURL,
+		token:  token,
+		client: http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// SetToken replaces the token requests are sent with, like after the bot's token was revoked.
+// Requests already sent keep the previous token.
+func (c *Client) SetToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = token
+}
+
+// currentToken returns the token requests are sent with right now
+func (c *Client) currentToken() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.token
+}
+
+// Error is returned by the Bot API for failed requests
+type Error struct {
+	Code        int
+	Description string
+	// RetryAfter is the number of seconds to wait before the request can be repeated, if it was flood limited
+	RetryAfter int
+	// MigrateToChatID is the new ID of a group that was upgraded to a supergroup
+	MigrateToChatID int64
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("telegram: %s (%d)", e.Description, e.Code)
+}
+
+// response is the envelope of every Bot API response
+type response struct {
+	Ok          bool            `json:"ok"`
+	Result      json.RawMessage `json:"result"`
+	ErrorCode   int             `json:"error_code"`
+	Description string          `json:"description"`
+	Parameters  struct {
+		RetryAfter      int   `json:"retry_after"`
+		MigrateToChatID int64 `json:"migrate_to_chat_id"`
+	} `json:"parameters"`
+}
+
+// Call invokes the Bot API method with the parameters sent as JSON and decodes its result into result, if not nil.
+// Failed requests return an *Error.
+func (c *Client) Call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, method, "application/json", bytes.NewReader(body), result)
+}
+
+// do sends the body of the content type to the method and decodes its result into result, if not nil
+func (c *Client) do(ctx context.Context, method string, contentType string, body io.Reader, result interface{}) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, requestTimeout)
+		defer cancel()
+	}
+
+	token := c.currentToken()
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/bot%s/%s", c.url, token, method), body)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		// the URL contains the token
+		return fmt.Errorf("failed to call %s: %v", method, errorWithoutToken(err, token))
+	}
+	defer resp.Body.Close()
+
+	var r response
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return fmt.Errorf("failed to decode %s response with status %d: %v", method, resp.StatusCode, err)
+	}
+	if !r.Ok {
+		return &Error{
+			Code:            r.ErrorCode,
+			Description:     r.Description,
+			RetryAfter:      r.Parameters.RetryAfter,
+			MigrateToChatID: r.Parameters.MigrateToChatID,
+		}
+	}
+
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(r.Result, result)
+}
+
+// errorWithoutToken returns the error with the token removed from its message
+func errorWithoutToken(err error, token string) error {
+	if token == "" || !strings.Contains(err.Error(), token) {
+		return err
+	}
+	return fmt.Errorf("%s", strings.Replace(err.Error(), token, "<token>", -1))
+}
+
+// GetMe returns the bot's user
+func (c *Client) GetMe() (User, error) {
+	var u User
+	err := c.Call(context.Background(), "getMe", struct{}{}, &u)
+	return u, err
+}
+
+// GetUpdates waits up to timeout for updates with IDs from offset on, long polling them
+func (c *Client) GetUpdates(ctx context.Context, offset int64, timeout time.Duration) ([]Update, error) {
+	params := struct {
+		Offset         int64    `json:"offset"`
+		Timeout        int      `json:"timeout"`
+		AllowedUpdates []string `json:"allowed_updates"`
+	}{
+		Offset:         offset,
+		Timeout:        int(timeout / time.Second),
+		AllowedUpdates: []string{"message", "callback_query"},
+	}
+
+	// The request has to outlive the long polling timeout
+	ctx, cancel := context.WithTimeout(ctx, timeout+requestTimeout)
+	defer cancel()
+
+	var updates []Update
+	err := c.Call(ctx, "getUpdates", params, &updates)
+	return updates, err
+}
+
+// SetWebhook makes Telegram send updates to the webhook instead of them being polled
+func (c *Client) SetWebhook(params SetWebhookParams) error {
+	return c.Call(context.Background(), "setWebhook", params, nil)
+}
+
+// DeleteWebhook makes Telegram stop sending updates to the webhook, so they can be polled again
+func (c *Client) DeleteWebhook() error {
+	return c.Call(context.Background(), "deleteWebhook", struct{}{}, nil)
+}
+
+// SendMessage sends a text message
+func (c *Client) SendMessage(params SendMessageParams) (Message, error) {
+	var m Message
+	err := c.Call(context.Background(), "sendMessage", params, &m)
+	return m, err
+}
+
+// EditMessageText replaces the text and inline keyboard of a message sent by the bot
+func (c *Client) EditMessageText(params EditMessageTextParams) error {
+	return c.Call(context.Background(), "editMessageText", params, nil)
+}
+
+// PinChatMessage pins the message, silently without notifying the chat's members if silent is true
+func (c *Client) PinChatMessage(chatID int64, messageID int, silent bool) error {
+	params := struct {
+		ChatID              int64 `json:"chat_id"`
+		MessageID           int   `json:"message_id"`
+		DisableNotification bool  `json:"disable_notification,omitempty"`
+	}{ChatID: chatID, MessageID: messageID, DisableNotification: silent}
+
+	return c.Call(context.Background(), "pinChatMessage", params, nil)
+}
+
+// UnpinChatMessage unpins the message
+func (c *Client) UnpinChatMessage(chatID int64, messageID int) error {
+	params := struct {
+		ChatID    int64 `json:"chat_id"`
+		MessageID int   `json:"message_id"`
+	}{ChatID: chatID, MessageID: messageID}
+
+	return c.Call(context.Background(), "unpinChatMessage", params, nil)
+}
+
+// SendChatAction shows the action, like ActionTyping, in the chat for a few seconds
+func (c *Client) SendChatAction(chatID int64, action string) error {
+	params := struct {
+		ChatID int64  `json:"chat_id"`
+		Action string `json:"action"`
+	}{ChatID: chatID, Action: action}
+
+	return c.Call(context.Background(), "sendChatAction", params, nil)
+}
+
+// AnswerCallbackQuery stops the button's progress indicator, showing the text as notification if it isn't empty
+func (c *Client) AnswerCallbackQuery(id string, text string) error {
+	params := struct {
+		ID   string `json:"callback_query_id"`
+		Text string `json:"text,omitempty"`
+	}{ID: id, Text: text}
+
+	return c.Call(context.Background(), "answerCallbackQuery", params, nil)
+}
+
+// SendDocument uploads the content as file
+func (c *Client) SendDocument(params SendDocumentParams) (Message, error) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+
+	fields := map[string]string{
+		"chat_id": strconv.FormatInt(params.ChatID, 10),
+		"caption": params.Caption,
+	}
+	if params.MessageThreadID != 0 {
+		fields["message_thread_id"] = strconv.Itoa(params.MessageThreadID)
+	}
+	if params.DisableNotification {
+		fields["disable_notification"] = "true"
+	}
+	for name, value := range fields {
+		if err := w.WriteField(name, value); err != nil {
+			return Message{}, err
+		}
+	}
+
+	file, err := w.CreateFormFile("document", params.Name)
+	if err != nil {
+		return Message{}, err
+	}
+	if _, err := file.Write(params.Content); err != nil {
+		return Message{}, err
+	}
+	if err := w.Close(); err != nil {
+		return Message{}, err
+	}
+
+	var m Message
+	err = c.do(context.Background(), "sendDocument", w.FormDataContentType(), &body, &m)
+	return m, err
+}
@@ -0,0 +1,57 @@
+package botapi_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/botapi"
+	"github.com/metalmatze/alertmanager-bot/pkg/botapi/botapitest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient(t *testing.T) {
+	s := botapitest.NewServer()
+	defer s.Close()
+	c := s.Client()
+
+	me, err := c.GetMe()
+	assert.NoError(t, err)
+	assert.Equal(t, botapitest.Me, me)
+
+	m, err := c.SendMessage(botapi.SendMessageParams{ChatID: -100, MessageThreadID: 7, Text: "hi"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-100), m.Chat.ID)
+	r := s.Requests("sendMessage")[0]
+	assert.Equal(t, "7", r.Param("message_thread_id"))
+	assert.Equal(t, "", r.Param("parse_mode"))
+
+	_, err = c.SendDocument(botapi.SendDocumentParams{ChatID: 1, Name: "alerts.txt", Content: []byte("firing")})
+	assert.NoError(t, err)
+	r = s.Requests("sendDocument")[0]
+	assert.Equal(t, "1", r.Param("chat_id"))
+	assert.Equal(t, map[string]string{"alerts.txt": "firing"}, r.Files)
+
+	c.SetToken("654321:revoked")
+	_, err = c.GetMe()
+	assert.Error(t, err)
+	c.SetToken(botapitest.Token)
+	_, err = c.GetMe()
+	assert.NoError(t, err)
+}
+
+func TestClientError(t *testing.T) {
+	s := botapitest.NewServer()
+	defer s.Close()
+	s.Handle("sendMessage", func(r botapitest.Request) (interface{}, *botapi.Error) {
+		return nil, &botapi.Error{Code: 429, Description: "Too Many Requests: retry after 3", RetryAfter: 3}
+	})
+
+	_, err := s.Client().SendMessage(botapi.SendMessageParams{ChatID: 1, Text: "hi"})
+	var apiErr *botapi.Error
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, 429, apiErr.Code)
+	assert.Equal(t, 3, apiErr.RetryAfter)
+
+	_, err = botapi.New("wrong", botapi.WithURL(s.URL)).GetMe()
+	assert.EqualError(t, err, "telegram: Unauthorized (401)")
+}
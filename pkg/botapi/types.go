@@ -0,0 +1,128 @@
+package botapi
+
+// Parse modes Telegram formats messages with, ModeDefault sends them as plain text
+const (
+	ModeDefault    = ""
+	ModeHTML       = "HTML"
+	ModeMarkdownV2 = "MarkdownV2"
+)
+
+// Types of chats
+const (
+	ChatPrivate    = "private"
+	ChatGroup      = "group"
+	ChatSupergroup = "supergroup"
+	ChatChannel    = "channel"
+)
+
+// ActionTyping shows that the bot is typing a reply
+const ActionTyping = "typing"
+
+// User is a Telegram user or bot
+type User struct {
+	ID        int    `json:"id"`
+	IsBot     bool   `json:"is_bot,omitempty"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name,omitempty"`
+	Username  string `json:"username,omitempty"`
+	// LanguageCode is the IETF language tag of the user's Telegram app
+	LanguageCode string `json:"language_code,omitempty"`
+}
+
+// Chat is a private chat, group, supergroup or channel
+type Chat struct {
+	ID   int64  `json:"id"`
+	Type string `json:"type"`
+	// Title of groups, supergroups and channels
+	Title string `json:"title,omitempty"`
+	// Username, FirstName and LastName of private chats
+	Username  string `json:"username,omitempty"`
+	FirstName string `json:"first_name,omitempty"`
+	LastName  string `json:"last_name,omitempty"`
+	// IsForum is true for supergroups with topics
+	IsForum bool `json:"is_forum,omitempty"`
+}
+
+// IsGroupChat returns true for all chats but private ones
+func (c Chat) IsGroupChat() bool {
+	return c.Type != ChatPrivate
+}
+
+// Message is a message sent to a chat
+type Message struct {
+	ID int `json:"message_id"`
+	// MessageThreadID is the forum topic of the message
+	MessageThreadID int  `json:"message_thread_id,omitempty"`
+	IsTopicMessage  bool `json:"is_topic_message,omitempty"`
+	// From is empty for messages sent to channels
+	From User   `json:"from"`
+	Date int64  `json:"date"`
+	Chat Chat   `json:"chat"`
+	Text string `json:"text,omitempty"`
+	// MigrateToChatID is the ID of the supergroup the group was upgraded to
+	MigrateToChatID int64 `json:"migrate_to_chat_id,omitempty"`
+}
+
+// CallbackQuery is sent when an inline keyboard button is pressed
+type CallbackQuery struct {
+	ID   string `json:"id"`
+	From User   `json:"from"`
+	// Message with the button, nil if it's too old
+	Message *Message `json:"message,omitempty"`
+	Data    string   `json:"data,omitempty"`
+}
+
+// Update is a new message or callback query received by the bot
+type Update struct {
+	ID            int64          `json:"update_id"`
+	Message       *Message       `json:"message,omitempty"`
+	CallbackQuery *CallbackQuery `json:"callback_query,omitempty"`
+}
+
+// InlineKeyboardMarkup is a keyboard shown below a message
+type InlineKeyboardMarkup struct {
+	InlineKeyboard [][]InlineKeyboardButton `json:"inline_keyboard"`
+}
+
+// InlineKeyboardButton is a button of an inline keyboard, which opens the URL or sends the callback data
+type InlineKeyboardButton struct {
+	Text         string `json:"text"`
+	URL          string `json:"url,omitempty"`
+	CallbackData string `json:"callback_data,omitempty"`
+}
+
+// SendMessageParams are the parameters of sendMessage
+type SendMessageParams struct {
+	ChatID              int64                 `json:"chat_id"`
+	MessageThreadID     int                   `json:"message_thread_id,omitempty"`
+	Text                string                `json:"text"`
+	ParseMode           string                `json:"parse_mode,omitempty"`
+	DisableNotification bool                  `json:"disable_notification,omitempty"`
+	ReplyMarkup         *InlineKeyboardMarkup `json:"reply_markup,omitempty"`
+}
+
+// EditMessageTextParams are the parameters of editMessageText
+type EditMessageTextParams struct {
+	ChatID      int64                 `json:"chat_id"`
+	MessageID   int                   `json:"message_id"`
+	Text        string                `json:"text"`
+	ParseMode   string                `json:"parse_mode,omitempty"`
+	ReplyMarkup *InlineKeyboardMarkup `json:"reply_markup,omitempty"`
+}
+
+// SendDocumentParams are the parameters of sendDocument, the document is uploaded as a file with the name
+type SendDocumentParams struct {
+	ChatID              int64
+	MessageThreadID     int
+	Name                string
+	Content             []byte
+	Caption             string
+	DisableNotification bool
+}
+
+// SetWebhookParams are the parameters of setWebhook
+type SetWebhookParams struct {
+	URL            string   `json:"url"`
+	SecretToken    string   `json:"secret_token,omitempty"`
+	AllowedUpdates []string `json:"allowed_updates,omitempty"`
+}
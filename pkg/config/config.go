@@ -0,0 +1,296 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"gopkg.in/yaml.v2"
+)
+
+// Stores the bot can persist its chats and routes in
+const (
+	StoreBolt   = "bolt"
+	StoreConsul = "consul"
+)
+
+// Config is the configuration file of the bot, see examples/config.yml
+type Config struct {
+	// Alertmanagers are queried by commands like /alerts, only a single one is supported yet
+	Alertmanagers []AlertmanagerConfig `yaml:"alertmanagers,omitempty"`
+	// ListenAddr is the address webhooks are received on
+	ListenAddr string          `yaml:"listen_addr,omitempty"`
+	Store      StoreConfig     `yaml:"store,omitempty"`
+	Telegram   TelegramConfig  `yaml:"telegram,omitempty"`
+	Templates  TemplatesConfig `yaml:"templates,omitempty"`
+	Webhook    WebhookConfig   `yaml:"webhook,omitempty"`
+	// Routes are named webhook routes in addition to the ones added with /route_add
+	Routes []RouteConfig `yaml:"routes,omitempty"`
+}
+
+// AlertmanagerConfig configures how to connect to an Alertmanager
+type AlertmanagerConfig struct {
+	URL URL `yaml:"url"`
+	// AuthConfig are the credentials requests to the Alertmanager are sent with, like for a proxy in front of it
+	AuthConfig `yaml:",inline"`
+}
+
+// StoreConfig configures the store chats and routes are persisted in
+type StoreConfig struct {
+	// Type is either bolt or consul
+	Type   string       `yaml:"type,omitempty"`
+	Bolt   BoltConfig   `yaml:"bolt,omitempty"`
+	Consul ConsulConfig `yaml:"consul,omitempty"`
+}
+
+// BoltConfig configures the bolt store
+type BoltConfig struct {
+	Path string `yaml:"path,omitempty"`
+}
+
+// ConsulConfig configures the consul store
+type ConsulConfig struct {
+	URL URL `yaml:"url,omitempty"`
+}
+
+// TelegramConfig configures the Telegram bot
+type TelegramConfig struct {
+	Token string `yaml:"token,omitempty"`
+	// TokenFile is read instead of Token, like a mounted Kubernetes secret
+	TokenFile string `yaml:"token_file,omitempty"`
+	// Admins are the IDs of the users allowed to command the bot
+	Admins           []int  `yaml:"admins,omitempty"`
+	AlertsPageSize   int    `yaml:"alerts_page_size,omitempty"`
+	AttachmentFormat string `yaml:"attachment_format,omitempty"`
+	AttachmentSize   int    `yaml:"attachment_size,omitempty"`
+	MaxMessages      int    `yaml:"max_messages,omitempty"`
+	// Workers are the number of chats notifications are sent to at the same time
+	Workers int `yaml:"workers,omitempty"`
+	// QueueSize is the number of notifications waiting to be sent at most
+	QueueSize int `yaml:"queue_size,omitempty"`
+	// Webhook makes Telegram send updates to the bot instead of the bot polling them
+	Webhook TelegramWebhookConfig `yaml:"webhook,omitempty"`
+}
+
+// TelegramWebhookConfig configures the webhook Telegram sends updates to
+type TelegramWebhookConfig struct {
+	// URL is the public HTTPS URL Telegram sends updates to, the updates are polled if it's empty
+	URL URL `yaml:"url,omitempty"`
+	// Path is the path updates are received on, it differs from the URL's path behind a proxy rewriting paths
+	Path string `yaml:"path,omitempty"`
+	// SecretToken is sent by Telegram with every update, a random one is used if it's empty
+	SecretToken string `yaml:"secret_token,omitempty"`
+}
+
+// TemplatesConfig configures the templates messages are rendered with
+type TemplatesConfig struct {
+	Paths []string `yaml:"paths,omitempty"`
+	Watch bool     `yaml:"watch,omitempty"`
+}
+
+// WebhookConfig configures the authentication Alertmanager has to send webhooks with
+type WebhookConfig struct {
+	AuthConfig `yaml:",inline"`
+}
+
+// AuthConfig is a bearer token and basic auth for HTTP authentication.
+// The secrets can be read from files instead, which are read again every time they're used.
+type AuthConfig struct {
+	BearerToken     string          `yaml:"bearer_token,omitempty"`
+	BearerTokenFile string          `yaml:"bearer_token_file,omitempty"`
+	BasicAuth       BasicAuthConfig `yaml:"basic_auth,omitempty"`
+}
+
+// BasicAuthConfig is a username and password for HTTP basic authentication
+type BasicAuthConfig struct {
+	Username     string `yaml:"username,omitempty"`
+	Password     string `yaml:"password,omitempty"`
+	PasswordFile string `yaml:"password_file,omitempty"`
+}
+
+// RouteConfig is a named webhook route, webhooks sent to /webhook/<name> are delivered to its chats
+type RouteConfig struct {
+	Name  string  `yaml:"name"`
+	Chats []int64 `yaml:"chats"`
+}
+
+// URL is a url.URL that can be read from YAML
+type URL struct {
+	*url.URL
+}
+
+// UnmarshalYAML parses the URL
+func (u *URL) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+
+	parsed, err := url.Parse(s)
+	if err != nil {
+		return err
+	}
+	u.URL = parsed
+	return nil
+}
+
+// MarshalYAML returns the URL as string
+func (u URL) MarshalYAML() (interface{}, error) {
+	if u.URL == nil {
+		return nil, nil
+	}
+	return u.String(), nil
+}
+
+// Load parses the YAML configuration into c, keeping the values of c the configuration leaves out
+func Load(s []byte, c *Config) error {
+	if err := yaml.UnmarshalStrict(s, c); err != nil {
+		return err
+	}
+	return c.Validate()
+}
+
+// LoadFile parses the YAML configuration file into c, keeping the values of c the file leaves out
+func LoadFile(path string, c *Config) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := Load(content, c); err != nil {
+		return fmt.Errorf("failed to load %s: %v", path, err)
+	}
+	return nil
+}
+
+// Validate returns an error for invalid values.
+// Values left empty are valid, as they may be passed as flags instead.
+func (c *Config) Validate() error {
+	if len(c.Alertmanagers) > 1 {
+		return fmt.Errorf("only a single alertmanager is supported yet, got %d", len(c.Alertmanagers))
+	}
+	for _, am := range c.Alertmanagers {
+		if am.URL.URL == nil {
+			return fmt.Errorf("alertmanager url is missing")
+		}
+		if err := am.AuthConfig.validate("alertmanager"); err != nil {
+			return err
+		}
+		if am.bearerToken() && am.BasicAuth.Username != "" {
+			return fmt.Errorf("alertmanager bearer_token and basic_auth are mutually exclusive")
+		}
+	}
+
+	switch c.Store.Type {
+	case "", StoreBolt, StoreConsul:
+	default:
+		return fmt.Errorf("unknown store %q, choose from %s or %s", c.Store.Type, StoreBolt, StoreConsul)
+	}
+
+	if c.Telegram.Token != "" && c.Telegram.TokenFile != "" {
+		return fmt.Errorf("telegram token and token_file are mutually exclusive")
+	}
+	if err := c.Telegram.Webhook.validate(); err != nil {
+		return err
+	}
+	if c.Telegram.AlertsPageSize < 0 || c.Telegram.AttachmentSize < 0 || c.Telegram.MaxMessages < 0 {
+		return fmt.Errorf("telegram alerts_page_size, attachment_size and max_messages can't be negative")
+	}
+	if c.Telegram.Workers < 0 || c.Telegram.QueueSize < 0 {
+		return fmt.Errorf("telegram workers and queue_size can't be negative")
+	}
+
+	if err := c.Webhook.AuthConfig.validate("webhook"); err != nil {
+		return err
+	}
+
+	names := map[string]bool{}
+	for _, r := range c.Routes {
+		if r.Name == "" {
+			return fmt.Errorf("route name is missing")
+		}
+		if names[r.Name] {
+			return fmt.Errorf("route %q is defined more than once", r.Name)
+		}
+		names[r.Name] = true
+		if len(r.Chats) == 0 {
+			return fmt.Errorf("route %q has no chats", r.Name)
+		}
+	}
+
+	return nil
+}
+
+// secretTokenRegexp matches the secret tokens Telegram accepts
+var secretTokenRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]{1,256}$`)
+
+// validate returns an error for URLs Telegram doesn't send updates to and paths taken by alertmanager's webhooks
+func (c TelegramWebhookConfig) validate() error {
+	if c.URL.URL != nil && c.URL.Scheme != "https" {
+		return fmt.Errorf("telegram webhook url has to be https, got %s", c.URL)
+	}
+	if c.Path != "" && (!strings.HasPrefix(c.Path, "/") || c.Path == "/" || strings.HasPrefix(c.Path, alertmanager.WebhookRoutePrefix)) {
+		return fmt.Errorf("telegram webhook path %q has to start with / and can't be / or below %s", c.Path, alertmanager.WebhookRoutePrefix)
+	}
+	if c.SecretToken != "" && !secretTokenRegexp.MatchString(c.SecretToken) {
+		return fmt.Errorf("telegram webhook secret_token can only have 1-256 characters A-Z, a-z, 0-9, _ and -")
+	}
+	return nil
+}
+
+// validate returns an error for secrets set together with their files and passwords without usernames
+func (c AuthConfig) validate(name string) error {
+	if c.BearerToken != "" && c.BearerTokenFile != "" {
+		return fmt.Errorf("%s bearer_token and bearer_token_file are mutually exclusive", name)
+	}
+	if c.BasicAuth.Password != "" && c.BasicAuth.PasswordFile != "" {
+		return fmt.Errorf("%s basic_auth password and password_file are mutually exclusive", name)
+	}
+	if c.BasicAuth.Username == "" && (c.BasicAuth.Password != "" || c.BasicAuth.PasswordFile != "") {
+		return fmt.Errorf("%s basic_auth password is set without a username", name)
+	}
+	return nil
+}
+
+// bearerToken returns true if a bearer token or its file is set
+func (c AuthConfig) bearerToken() bool {
+	return c.BearerToken != "" || c.BearerTokenFile != ""
+}
+
+// Credentials returns the credentials, reading the secrets from their files
+func (c AuthConfig) Credentials() (alertmanager.Credentials, error) {
+	token, err := readSecret(c.BearerToken, c.BearerTokenFile)
+	if err != nil {
+		return alertmanager.Credentials{}, err
+	}
+	password, err := readSecret(c.BasicAuth.Password, c.BasicAuth.PasswordFile)
+	if err != nil {
+		return alertmanager.Credentials{}, err
+	}
+
+	return alertmanager.Credentials{
+		BearerToken: token,
+		Username:    c.BasicAuth.Username,
+		Password:    password,
+	}, nil
+}
+
+// ReadToken returns the token, reading it from its file
+func (c TelegramConfig) ReadToken() (string, error) {
+	return readSecret(c.Token, c.TokenFile)
+}
+
+// readSecret returns the content of the file without surrounding whitespace, or the value if there's no file
+func readSecret(value string, file string) (string, error) {
+	if file == "" {
+		return value, nil
+	}
+
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret: %v", err)
+	}
+	return strings.TrimSpace(string(content)), nil
+}
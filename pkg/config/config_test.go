@@ -0,0 +1,87 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadFile(t *testing.T) {
+	var c Config
+	assert.NoError(t, LoadFile("../../examples/config.yml", &c))
+
+	assert.Equal(t, "http://localhost:9093/", c.Alertmanagers[0].URL.String())
+	assert.Equal(t, StoreBolt, c.Store.Type)
+	assert.Equal(t, []int{123456789}, c.Telegram.Admins)
+	assert.Equal(t, []RouteConfig{{Name: "team-db", Chats: []int64{-100123456789}}}, c.Routes)
+}
+
+func TestLoadKeepsValues(t *testing.T) {
+	c := Config{
+		ListenAddr: "0.0.0.0:8080",
+		Telegram:   TelegramConfig{Token: "token", MaxMessages: 5, Admins: []int{1}},
+	}
+
+	assert.NoError(t, Load([]byte("telegram:\n  admins: [2, 3]\n  max_messages: 3\n"), &c))
+	assert.Equal(t, "0.0.0.0:8080", c.ListenAddr)
+	assert.Equal(t, TelegramConfig{Token: "token", MaxMessages: 3, Admins: []int{2, 3}}, c.Telegram)
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := map[string]string{
+		"unknown field":      "telegram:\n  tokn: x\n",
+		"two alertmanagers":  "alertmanagers:\n  - url: http://a\n  - url: http://b\n",
+		"alertmanager url":   "alertmanagers:\n  - {}\n",
+		"store":              "store:\n  type: redis\n",
+		"negative page size": "telegram:\n  alerts_page_size: -1\n",
+		"password only":      "webhook:\n  basic_auth:\n    password: x\n",
+		"password file only": "webhook:\n  basic_auth:\n    password_file: /x\n",
+		"token and file":     "telegram:\n  token: x\n  token_file: /x\n",
+		"bearer and file":    "webhook:\n  bearer_token: x\n  bearer_token_file: /x\n",
+		"bearer and basic":   "alertmanagers:\n  - url: http://a\n    bearer_token: x\n    basic_auth:\n      username: x\n",
+		"webhook http":       "telegram:\n  webhook:\n    url: http://bot.example.com/telegram\n",
+		"webhook path":       "telegram:\n  webhook:\n    path: /webhook/telegram\n",
+		"webhook secret":     "telegram:\n  webhook:\n    secret_token: not secret\n",
+		"route name":         "routes:\n  - chats: [1]\n",
+		"duplicate route":    "routes:\n  - name: db\n    chats: [1]\n  - name: db\n    chats: [2]\n",
+		"route chats":        "routes:\n  - name: db\n",
+	}
+
+	for name, config := range tests {
+		var c Config
+		assert.Error(t, Load([]byte(config), &c), name)
+	}
+}
+
+func TestSecretFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "alertmanager-bot")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	token := filepath.Join(dir, "token")
+	assert.NoError(t, ioutil.WriteFile(token, []byte("secret\n"), 0600))
+
+	c := TelegramConfig{TokenFile: token}
+	read, err := c.ReadToken()
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", read)
+
+	auth := AuthConfig{BearerTokenFile: token, BasicAuth: BasicAuthConfig{Username: "bot", Password: "password"}}
+	creds, err := auth.Credentials()
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", creds.BearerToken)
+	assert.Equal(t, "bot", creds.Username)
+	assert.Equal(t, "password", creds.Password)
+
+	// rotated secrets are read again
+	assert.NoError(t, ioutil.WriteFile(token, []byte("rotated"), 0600))
+	read, err = c.ReadToken()
+	assert.NoError(t, err)
+	assert.Equal(t, "rotated", read)
+
+	_, err = TelegramConfig{TokenFile: filepath.Join(dir, "missing")}.ReadToken()
+	assert.Error(t, err)
+}
@@ -0,0 +1,66 @@
+package telegram
+
+import (
+	"strings"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/metalmatze/alertmanager-bot/pkg/botapi"
+)
+
+// reply sends a plain text message to the recipient, failures are logged
+func (b *Bot) reply(recipient Recipient, text string) {
+	err := b.throttle.do(recipient.ChatID, func() error {
+		_, err := b.telegram.SendMessage(botapi.SendMessageParams{
+			ChatID:          recipient.ChatID,
+			MessageThreadID: recipient.ThreadID,
+			Text:            text,
+		})
+		return err
+	})
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to send reply", "chat_id", recipient.ChatID, "err", err)
+	}
+}
+
+// sendKeyboard sends an HTML message with an inline keyboard to the recipient
+func (b *Bot) sendKeyboard(recipient Recipient, text string, keyboard [][]botapi.InlineKeyboardButton) error {
+	return b.throttle.do(recipient.ChatID, func() error {
+		_, err := b.telegram.SendMessage(botapi.SendMessageParams{
+			ChatID:          recipient.ChatID,
+			MessageThreadID: recipient.ThreadID,
+			Text:            text,
+			ParseMode:       botapi.ModeHTML,
+			ReplyMarkup:     &botapi.InlineKeyboardMarkup{InlineKeyboard: keyboard},
+		})
+		return err
+	})
+}
+
+// editMessage replaces the text and inline keyboard of a message sent by the bot
+func (b *Bot) editMessage(chat botapi.Chat, messageID int, text string, keyboard [][]botapi.InlineKeyboardButton) error {
+	params := botapi.EditMessageTextParams{
+		ChatID:    chat.ID,
+		MessageID: messageID,
+		Text:      text,
+		ParseMode: botapi.ModeHTML,
+	}
+	if keyboard != nil {
+		params.ReplyMarkup = &botapi.InlineKeyboardMarkup{InlineKeyboard: keyboard}
+	}
+
+	edit := func() error { return b.telegram.EditMessageText(params) }
+
+	err := b.throttle.do(chat.ID, edit)
+	if isEntitiesError(err) {
+		level.Warn(b.logger).Log("msg", "telegram can't parse the message, editing it as plain text", "err", err)
+		// a message can only be edited to a single message, keep what fits
+		params.Text = splitText(plainText(text), ParseModePlain, maxMessageLength)[0]
+		params.ParseMode = botapi.ModeDefault
+		err = b.throttle.do(chat.ID, edit)
+	}
+	if err != nil && strings.Contains(err.Error(), "message is not modified") {
+		// the message already shows the same page
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,130 @@
+package telegram
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/alertmanager/template"
+)
+
+// Formats of files replies are attached as
+const (
+	AttachmentText = "txt"
+	AttachmentHTML = "html"
+	AttachmentCSV  = "csv"
+)
+
+const attachmentHTMLDocument = `<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>%s</title></head>
+<body style="font-family: sans-serif; white-space: pre-wrap">%s</body>
+</html>
+`
+
+// severityOrder sorts common severities from most to least severe, others come last
+var severityOrder = map[string]int{"critical": 0, "page": 1, "error": 2, "warning": 3, "info": 4, "none": 5}
+
+// severityCount is the number of alerts with a severity
+type severityCount struct {
+	Severity string
+	Count    int
+}
+
+// countSeverities counts the alerts by their severity label, most severe first
+func countSeverities(alerts template.Alerts) []severityCount {
+	counts := map[string]int{}
+	for _, a := range alerts {
+		severity := a.Labels["severity"]
+		if severity == "" {
+			severity = "unknown"
+		}
+		counts[severity]++
+	}
+
+	result := make([]severityCount, 0, len(counts))
+	for severity, count := range counts {
+		result = append(result, severityCount{Severity: severity, Count: count})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		oi, ok := severityOrder[result[i].Severity]
+		if !ok {
+			oi = len(severityOrder)
+		}
+		oj, ok := severityOrder[result[j].Severity]
+		if !ok {
+			oj = len(severityOrder)
+		}
+		if oi != oj {
+			return oi < oj
+		}
+		return result[i].Severity < result[j].Severity
+	})
+
+	return result
+}
+
+// alertsSummary summarizes the alerts in a single line in the language like "312 alerts, 14 critical, 298 warning"
+func alertsSummary(lang string, alerts template.Alerts) string {
+	parts := []string{alertsCount(lang, len(alerts))}
+
+	for _, c := range countSeverities(alerts) {
+		parts = append(parts, fmt.Sprintf("%d %s", c.Count, c.Severity))
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// renderAttachment renders the alerts as a file in the given format, titled in the language.
+// out is the alerts already rendered by the telegram.default template.
+func renderAttachment(format string, lang string, data *template.Data, out string) (string, []byte, error) {
+	switch format {
+	case AttachmentHTML:
+		return "alerts.html", []byte(fmt.Sprintf(attachmentHTMLDocument, html.EscapeString(alertsSummary(lang, data.Alerts)), out)), nil
+	case AttachmentCSV:
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		w.Write([]string{"status", "alertname", "severity", "startsAt", "endsAt", "labels", "summary", "generatorURL"})
+
+		for _, a := range data.Alerts {
+			var labels []string
+			for _, p := range a.Labels.Remove([]string{"alertname", "severity"}).SortedPairs() {
+				labels = append(labels, fmt.Sprintf("%s=%q", p.Name, p.Value))
+			}
+
+			summary := a.Annotations["summary"]
+			if summary == "" {
+				summary = a.Annotations["message"]
+			}
+			if summary == "" {
+				summary = a.Annotations["description"]
+			}
+
+			var endsAt string
+			if !a.EndsAt.IsZero() {
+				endsAt = a.EndsAt.Format(time.RFC3339)
+			}
+
+			w.Write([]string{
+				a.Status,
+				a.Labels["alertname"],
+				a.Labels["severity"],
+				a.StartsAt.Format(time.RFC3339),
+				endsAt,
+				strings.Join(labels, " "),
+				summary,
+				a.GeneratorURL,
+			})
+		}
+
+		w.Flush()
+		return "alerts.csv", buf.Bytes(), w.Error()
+	default:
+		return "alerts.txt", []byte(plainText(out)), nil
+	}
+}
@@ -0,0 +1,78 @@
+package telegram
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/metalmatze/alertmanager-bot/pkg/botapi/botapitest"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAlertsSummary(t *testing.T) {
+	alerts := template.Alerts{
+		{Labels: template.KV{"severity": "warning"}},
+		{Labels: template.KV{"severity": "critical"}},
+		{Labels: template.KV{"severity": "custom"}},
+		{Labels: template.KV{"severity": "warning"}},
+		{Labels: template.KV{}},
+	}
+
+	assert.Equal(t, "5 alerts, 1 critical, 2 warning, 1 custom, 1 unknown", alertsSummary(LanguageEnglish, alerts))
+	assert.Equal(t, "1 alert, 1 warning", alertsSummary(LanguageEnglish, alerts[:1]))
+}
+
+func TestRenderAttachmentCSV(t *testing.T) {
+	data := &template.Data{Alerts: template.Alerts{{
+		Status:      "firing",
+		Labels:      template.KV{"alertname": "Fire", "severity": "critical", "job": "x"},
+		Annotations: template.KV{"message": "Something is on fire"},
+		StartsAt:    time.Date(2018, 11, 4, 22, 43, 58, 0, time.UTC),
+	}}}
+
+	name, content, err := renderAttachment(AttachmentCSV, LanguageEnglish, data, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "alerts.csv", name)
+	assert.Equal(t,
+		"status,alertname,severity,startsAt,endsAt,labels,summary,generatorURL\n"+
+			"firing,Fire,critical,2018-11-04T22:43:58Z,,\"job=\"\"x\"\"\",Something is on fire,\n",
+		string(content),
+	)
+
+	name, content, err = renderAttachment(AttachmentText, LanguageEnglish, data, "<b>FIRING</b> &amp; more")
+	assert.NoError(t, err)
+	assert.Equal(t, "alerts.txt", name)
+	assert.Equal(t, "FIRING & more", string(content))
+}
+
+func TestRenderAttachmentHTML(t *testing.T) {
+	data := &template.Data{Alerts: template.Alerts{{
+		Status: "firing",
+		Labels: template.KV{"alertname": "Fire", "severity": "<p1> & up"},
+	}}}
+
+	name, content, err := renderAttachment(AttachmentHTML, LanguageEnglish, data, "<b>FIRING</b>")
+	assert.NoError(t, err)
+	assert.Equal(t, "alerts.html", name)
+	assert.Contains(t, string(content), "<title>1 alert, 1 &lt;p1&gt; &amp; up</title>")
+	assert.Contains(t, string(content), "<b>FIRING</b>")
+}
+
+func TestSendDocument(t *testing.T) {
+	s := botapitest.NewServer()
+	defer s.Close()
+
+	b := &Bot{logger: log.NewNopLogger(), telegram: s.Client()}
+
+	_, err := b.sendDocument(Recipient{ChatID: -1, ThreadID: 7}, "alerts.txt", []byte("firing"), "1 alert, 1 critical", true)
+	assert.NoError(t, err)
+
+	// the summary is the document's caption instead of a message of its own
+	assert.Empty(t, s.Requests("sendMessage"))
+	r := s.Requests("sendDocument")[0]
+	assert.Equal(t, "1 alert, 1 critical", r.Param("caption"))
+	assert.Equal(t, "7", r.Param("message_thread_id"))
+	assert.Equal(t, "true", r.Param("disable_notification"))
+	assert.Equal(t, map[string]string{"alerts.txt": "firing"}, r.Files)
+}
@@ -3,21 +3,24 @@ package telegram
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"net/url"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/docker/libkv/store"
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
-	"github.com/hako/durafmt"
 	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/metalmatze/alertmanager-bot/pkg/botapi"
 	"github.com/oklog/run"
-	"github.com/prometheus/alertmanager/notify"
 	"github.com/prometheus/alertmanager/template"
 	"github.com/prometheus/alertmanager/types"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/tucnak/telebot"
+	"github.com/prometheus/common/model"
 )
 
 const (
@@ -26,13 +29,23 @@ const (
 	commandHelp  = "/help"
 	commandChats = "/chats"
 
-	commandStatus     = "/status"
-	commandAlerts     = "/alerts"
-	commandSilences   = "/silences"
-	commandSilenceAdd = "/silence_add"
-	commandSilence    = "/silence"
-	commandSilenceDel = "/silence_del"
-	commandFilters    = "/filters"
+	commandStatus       = "/status"
+	commandAlerts       = "/alerts"
+	commandGroups       = "/groups"
+	commandSilences     = "/silences"
+	commandSilenceAdd   = "/silence_add"
+	commandSilence      = "/silence"
+	commandSilenceDel   = "/silence_del"
+	commandFilters      = "/filters"
+	commandTemplate     = "/template"
+	commandTemplateTest = "/template_test"
+	commandLang         = "/lang"
+	commandSilent       = "/silent"
+	commandPin          = "/pin"
+	commandRepeat       = "/repeat"
+	commandRoutes       = "/routes"
+	commandRouteAdd     = "/route_add"
+	commandRouteDel     = "/route_del"
 
 	responseStart   = "Hey, %s! I will now keep you up to date!\nEnabled filters: %s\n" + commandHelp
 	responseStop    = "Alright, %s! I won't talk to you again.\n" + commandHelp
@@ -63,35 +76,97 @@ Available commands:
 ` + commandStart + ` [label=values ...] - Subscribe for alerts and set filters.
 ` + commandStop + ` - Unsubscribe for alerts.
 ` + commandStatus + ` - Print the current status.
-` + commandAlerts + ` - List all alerts.
+` + commandAlerts + ` [matchers ...] - List all alerts or those matching label=value, label!=value, label=~regex or label!~regex.
+` + commandGroups + ` [matchers ...] - List alerts grouped by receiver and group labels.
 ` + commandSilences + ` - List all silences.
 ` + commandChats + ` - List all users and group chats that subscribed.
 ` + commandFilters + ` - List more info about filters.
+` + commandTemplate + ` [name] - Show or choose the template alerts are sent with.
+` + commandTemplateTest + ` [name] - Render sample and current alerts with this chat's or the given template.
+` + commandLang + ` [language] - Show or choose the language I reply in.
+` + commandSilent + ` [matchers ...|off] - Show or choose which alerts are sent without sound.
+` + commandPin + ` [on|off] - Show or choose if critical alerts are pinned until they're resolved.
+` + commandRepeat + ` [interval|off] - Show or choose how long unchanged alerts aren't sent again.
+` + commandRoutes + ` - List all webhook routes.
+` + commandRouteAdd + ` name [chat_id] - Send webhooks for /webhook/name to this or the given chat.
+` + commandRouteDel + ` name [chat_id] - Stop sending webhooks for /webhook/name to this or the given chat.
 `
 )
 
 // BotChatStore is all the Bot needs to store and read
 type BotChatStore interface {
 	List() ([]AugmentedChat, error)
+	Get(r Recipient) (AugmentedChat, error)
 	Add(AugmentedChat) error
 	Remove(AugmentedChat) error
 }
 
+// BotPinStore is all the Bot needs to store and read pinned messages
+type BotPinStore interface {
+	List() ([]Pin, error)
+	Get(r Recipient, groupKey string) (Pin, error)
+	Add(Pin) error
+	Remove(Pin) error
+}
+
+// BotRouteStore is all the Bot needs to store and read webhook routes
+type BotRouteStore interface {
+	List() ([]Route, error)
+	Get(name string) (Route, error)
+	Add(Route) error
+	Remove(Route) error
+}
+
 // Bot runs the alertmanager telegram
 type Bot struct {
 	addr         string
-	admins       []int // must be kept sorted
 	alertmanager *url.URL
-	templates    *template.Template
 	chats        BotChatStore
+	routes       BotRouteStore
+	pins         BotPinStore
 	logger       log.Logger
+	maxMessages  int
+	pageSize     int
 	revision     string
 	startTime    time.Time
 
-	telegram *telebot.Bot
+	attachmentFormat string
+	attachmentSize   int
+	alertFilters     alertFilters
+
+	// alertmanagerClient sends the requests to the alertmanager
+	alertmanagerClient *http.Client
 
-	commandsCounter *prometheus.CounterVec
-	webhooksCounter prometheus.Counter
+	// mu guards what can be replaced while the bot runs
+	mu           sync.RWMutex
+	admins       []int // must be kept sorted
+	templates    *Templates
+	staticRoutes map[string]Route
+
+	telegram *botapi.Client
+	// telegramCheck is the last result of the readiness check calling Telegram
+	telegramCheck telegramCheck
+	// me is the bot's Telegram user
+	me botapi.User
+	// messages and callbacks are the received updates waiting to be handled
+	messages  chan botapi.Message
+	callbacks chan botapi.CallbackQuery
+	// updatesURL is where Telegram sends updates with updatesSecret, they're polled if it's nil
+	updatesURL    *url.URL
+	updatesSecret string
+
+	// registerer registers the bot's metrics
+	registerer prometheus.Registerer
+	metrics    *metrics
+
+	// throttle limits the requests sent to Telegram
+	throttle *throttle
+	// queue are the notifications waiting to be sent to the chats by the workers
+	queue     *notificationQueue
+	queueSize int
+	workers   int
+	// deliveries are the alert groups last delivered to the chats
+	deliveries deliveries
 }
 
 // BotOption passed to NewBot to change the default instance
@@ -99,33 +174,58 @@ type BotOption func(b *Bot)
 
 // NewBot creates a Bot with the UserStore and telegram telegram
 func NewBot(chats BotChatStore, token string, admin int, opts ...BotOption) (*Bot, error) {
-	bot, err := telebot.NewBot(token)
+	b := &Bot{
+		logger:       log.NewNopLogger(),
+		telegram:     botapi.New(token),
+		chats:        chats,
+		addr:         "127.0.0.1:8080",
+		admins:       []int{admin},
+		alertmanager: &url.URL{Host: "localhost:9093"},
+		maxMessages:  5,
+		messages:     make(chan botapi.Message, 100),
+		callbacks:    make(chan botapi.CallbackQuery, 100),
+
+		alertmanagerClient: http.DefaultClient,
+		attachmentFormat:   AttachmentText,
+		registerer:         prometheus.NewRegistry(),
+		queueSize:          1000,
+		workers:            4,
+		// TODO: initialize templates with default?
+	}
+
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	m, err := newMetrics(b.registerer)
 	if err != nil {
 		return nil, err
 	}
+	b.metrics = m
+	b.throttle = newThrottle(m.throttled)
+	b.queue = newNotificationQueue(b.queueSize, m.queued)
 
-	commandsCounter := prometheus.NewCounterVec(prometheus.CounterOpts{
+	updatesQueued := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
 		Namespace: "alertmanagerbot",
-		Name:      "commands_total",
-		Help:      "Number of commands received by command name",
-	}, []string{"command"})
-	if err := prometheus.Register(commandsCounter); err != nil {
+		Name:      "updates_queued",
+		Help:      "Number of messages and callbacks received from Telegram waiting to be handled",
+	}, func() float64 {
+		return float64(len(b.messages) + len(b.callbacks))
+	})
+	if err := b.registerer.Register(updatesQueued); err != nil {
 		return nil, err
 	}
 
-	b := &Bot{
-		logger:          log.NewNopLogger(),
-		telegram:        bot,
-		chats:           chats,
-		addr:            "127.0.0.1:8080",
-		admins:          []int{admin},
-		alertmanager:    &url.URL{Host: "localhost:9093"},
-		commandsCounter: commandsCounter,
-		// TODO: initialize templates with default?
+	me, err := b.telegram.GetMe()
+	if err != nil {
+		return nil, err
 	}
+	b.me = me
 
-	for _, opt := range opts {
-		opt(b)
+	if b.updatesURL != nil && b.updatesSecret == "" {
+		if b.updatesSecret, err = randomSecretToken(); err != nil {
+			return nil, err
+		}
 	}
 
 	return b, nil
@@ -138,6 +238,26 @@ func WithLogger(l log.Logger) BotOption {
 	}
 }
 
+// WithRegisterer registers the bot's metrics with r instead of a registry of its own
+func WithRegisterer(r prometheus.Registerer) BotOption {
+	return func(b *Bot) {
+		b.registerer = r
+	}
+}
+
+// WithTelegramClient sets the client for Telegram's Bot API, like one of a local Bot API server
+func WithTelegramClient(c *botapi.Client) BotOption {
+	return func(b *Bot) {
+		b.telegram = c
+	}
+}
+
+// SetToken replaces the token the bot calls Telegram's Bot API with,
+// notifications waiting in the queue are sent with the new token.
+func (b *Bot) SetToken(token string) {
+	b.telegram.SetToken(token)
+}
+
 // WithAddr sets the internal listening addr of the bot's web server receiving webhooks
 func WithAddr(addr string) BotOption {
 	return func(b *Bot) {
@@ -152,13 +272,134 @@ func WithAlertmanager(u *url.URL) BotOption {
 	}
 }
 
-// WithTemplates uses Alertmanager template to render messages for Telegram
-func WithTemplates(t *template.Template) BotOption {
+// WithAlertmanagerClient sets the client requests to the Alertmanager are sent with, like one sending credentials
+func WithAlertmanagerClient(c *http.Client) BotOption {
+	return func(b *Bot) {
+		b.alertmanagerClient = c
+	}
+}
+
+// WithTemplates uses Alertmanager templates to render messages for Telegram
+func WithTemplates(t *Templates) BotOption {
 	return func(b *Bot) {
 		b.templates = t
 	}
 }
 
+// SetTemplates replaces the templates messages are rendered with,
+// messages being rendered keep using the previous templates.
+func (b *Bot) SetTemplates(t *Templates) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.templates = t
+}
+
+// currentTemplates returns the templates messages are rendered with right now
+func (b *Bot) currentTemplates() *Templates {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.templates
+}
+
+// WithRouteStore enables named webhook routes stored in the given store
+func WithRouteStore(routes BotRouteStore) BotOption {
+	return func(b *Bot) {
+		b.routes = routes
+	}
+}
+
+// WithPinStore enables pinning critical alert groups in chats that opted in with /pin, pins are stored in the given store
+func WithPinStore(pins BotPinStore) BotOption {
+	return func(b *Bot) {
+		b.pins = pins
+	}
+}
+
+// WithStaticRoutes adds named webhook routes that can't be changed with /route_add and /route_del,
+// like the ones defined in the configuration file.
+func WithStaticRoutes(routes ...Route) BotOption {
+	return func(b *Bot) {
+		b.SetStaticRoutes(routes...)
+	}
+}
+
+// SetStaticRoutes replaces the named webhook routes that can't be changed with commands
+func (b *Bot) SetStaticRoutes(routes ...Route) {
+	static := make(map[string]Route, len(routes))
+	for _, r := range routes {
+		static[r.Name] = r
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.staticRoutes = static
+}
+
+// staticRoute returns the static route with the name, if there's one
+func (b *Bot) staticRoute(name string) (Route, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	r, ok := b.staticRoutes[name]
+	return r, ok
+}
+
+// listStaticRoutes returns the static routes sorted by name
+func (b *Bot) listStaticRoutes() []Route {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	routes := make([]Route, 0, len(b.staticRoutes))
+	for _, r := range b.staticRoutes {
+		routes = append(routes, r)
+	}
+	sort.Slice(routes, func(i, j int) bool { return routes[i].Name < routes[j].Name })
+	return routes
+}
+
+// WithMaxMessages sets how many messages a long message may be split into
+// before it's sent as a file attachment instead. Zero never sends attachments.
+func WithMaxMessages(n int) BotOption {
+	return func(b *Bot) {
+		b.maxMessages = n
+	}
+}
+
+// WithWorkers sets the number of chats notifications are sent to at the same time
+func WithWorkers(n int) BotOption {
+	return func(b *Bot) {
+		if n > 0 {
+			b.workers = n
+		}
+	}
+}
+
+// WithQueueSize sets the number of notifications waiting to be sent at most, further ones are dropped
+func WithQueueSize(n int) BotOption {
+	return func(b *Bot) {
+		if n > 0 {
+			b.queueSize = n
+		}
+	}
+}
+
+// WithAlertsPageSize sets the number of alerts shown on each page of /alerts
+func WithAlertsPageSize(n int) BotOption {
+	return func(b *Bot) {
+		if n > 0 {
+			b.pageSize = n
+		}
+	}
+}
+
+// WithAttachments sends /alerts replies longer than size characters
+// as a file in the given format (txt, html or csv) instead.
+func WithAttachments(format string, size int) BotOption {
+	return func(b *Bot) {
+		b.attachmentFormat = format
+		b.attachmentSize = size
+	}
+}
+
 // WithRevision is setting the Bot's revision for status commands
 func WithRevision(r string) BotOption {
 	return func(b *Bot) {
@@ -182,48 +423,78 @@ func WithExtraAdmins(ids ...int) BotOption {
 	}
 }
 
+// SetAdmins replaces the IDs of the users allowed to command the bot
+func (b *Bot) SetAdmins(ids ...int) {
+	admins := append([]int(nil), ids...)
+	sort.Ints(admins)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.admins = admins
+}
+
 // SendAdminMessage to the admin's ID with a message
 func (b *Bot) SendAdminMessage(adminID int, message string) {
-	b.telegram.SendMessage(telebot.User{ID: adminID}, message, nil)
+	// the private chat with a user has the user's ID
+	b.reply(Recipient{ChatID: int64(adminID)}, message)
 }
 
 // isAdminID returns whether id is one of the configured admin IDs.
 func (b *Bot) isAdminID(id int) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
 	i := sort.SearchInts(b.admins, id)
 	return i < len(b.admins) && b.admins[i] == id
 }
 
 // Run the telegram and listen to messages send to the telegram
-func (b *Bot) Run(ctx context.Context, webhooks <-chan notify.WebhookMessage) error {
-	commandSuffix := fmt.Sprintf("@%s", b.telegram.Identity.Username)
-
-	commands := map[string]func(message telebot.Message){
-		commandStart:    b.handleStart,
-		commandStop:     b.handleStop,
-		commandHelp:     b.handleHelp,
-		commandChats:    b.handleChats,
-		commandStatus:   b.handleStatus,
-		commandAlerts:   b.handleAlerts,
-		commandSilences: b.handleSilences,
-		commandFilters:  b.handleFilters,
+func (b *Bot) Run(ctx context.Context, webhooks <-chan alertmanager.Webhook) error {
+	commandSuffix := fmt.Sprintf("@%s", b.me.Username)
+
+	commands := map[string]func(message botapi.Message){
+		commandStart:        b.handleStart,
+		commandStop:         b.handleStop,
+		commandHelp:         b.handleHelp,
+		commandChats:        b.handleChats,
+		commandStatus:       b.handleStatus,
+		commandAlerts:       b.handleAlerts,
+		commandGroups:       b.handleGroups,
+		commandSilences:     b.handleSilences,
+		commandFilters:      b.handleFilters,
+		commandTemplate:     b.handleTemplate,
+		commandTemplateTest: b.handleTemplateTest,
+		commandLang:         b.handleLang,
+		commandSilent:       b.handleSilent,
+		commandPin:          b.handlePin,
+		commandRepeat:       b.handleRepeat,
+		commandRoutes:       b.handleRoutes,
+		commandRouteAdd:     b.handleRouteAdd,
+		commandRouteDel:     b.handleRouteDel,
 	}
 
 	// init counters with 0
 	for command := range commands {
-		b.commandsCounter.WithLabelValues(command).Add(0)
+		b.metrics.commands.WithLabelValues(command).Add(0)
 	}
 
-	process := func(message telebot.Message) error {
-		if message.IsService() {
+	process := func(message botapi.Message) error {
+		if message.MigrateToChatID != 0 {
+			b.migrateChat(message.Chat.ID, message.MigrateToChatID)
+			return nil
+		}
+
+		// service messages, like members joining, have no text
+		if message.Text == "" {
 			return nil
 		}
 
-		if !b.isAdminID(message.Sender.ID) {
-			b.commandsCounter.WithLabelValues("dropped").Inc()
+		if !b.isAdminID(message.From.ID) {
+			b.metrics.commands.WithLabelValues("dropped").Inc()
 			return fmt.Errorf("dropped message from forbidden sender")
 		}
 
-		if err := b.telegram.SendChatAction(message.Chat, telebot.Typing); err != nil {
+		if err := b.telegram.SendChatAction(message.Chat.ID, botapi.ActionTyping); err != nil {
 			return err
 		}
 
@@ -238,23 +509,52 @@ func (b *Bot) Run(ctx context.Context, webhooks <-chan notify.WebhookMessage) er
 		handler, ok := commands[text]
 
 		if !ok {
-			b.commandsCounter.WithLabelValues("incomprehensible").Inc()
-			b.telegram.SendMessage(
-				message.Chat,
-				"Sorry, I don't understand...",
-				nil,
-			)
+			b.metrics.commands.WithLabelValues("incomprehensible").Inc()
+			b.reply(messageRecipient(message), tr(b.language(messageRecipient(message), message.From), "Sorry, I don't understand..."))
 			return nil
 		}
 
-		b.commandsCounter.WithLabelValues(text).Inc()
+		b.metrics.commands.WithLabelValues(text).Inc()
 		handler(message)
 
 		return nil
 	}
 
-	messages := make(chan telebot.Message, 100)
-	b.telegram.Listen(messages, time.Second)
+	callbacks := map[string]func(callback botapi.CallbackQuery, lang string, args []string) string{
+		callbackAlerts: b.handleAlertsCallback,
+		callbackGroups: b.handleGroupsCallback,
+	}
+
+	processCallback := func(callback botapi.CallbackQuery) error {
+		if !b.isAdminID(callback.From.ID) {
+			b.metrics.commands.WithLabelValues("dropped").Inc()
+			return fmt.Errorf("dropped callback from forbidden sender")
+		}
+
+		// Telegram doesn't send messages that are too old, so there's nothing to update
+		if callback.Message == nil {
+			return b.telegram.AnswerCallbackQuery(callback.ID, "")
+		}
+
+		// Callback data is the handler's name followed by its arguments, alerts:1:key
+		args := strings.Split(callback.Data, ":")
+
+		lang := b.language(messageRecipient(*callback.Message), callback.From)
+
+		var reply string
+		if handler, ok := callbacks[args[0]]; ok {
+			reply = handler(callback, lang, args[1:])
+		} else {
+			reply = tr(lang, "Sorry, I don't understand...")
+		}
+
+		return b.telegram.AnswerCallbackQuery(callback.ID, reply)
+	}
+
+	if err := b.receiveUpdates(ctx); err != nil {
+		return fmt.Errorf("failed to receive updates: %v", err)
+	}
+	defer b.stopUpdates()
 
 	var gr run.Group
 	{
@@ -263,19 +563,43 @@ func (b *Bot) Run(ctx context.Context, webhooks <-chan notify.WebhookMessage) er
 		}, func(err error) {
 		})
 	}
+	{
+		gr.Add(func() error {
+			return b.sendNotifications(ctx)
+		}, func(err error) {
+		})
+	}
+	{
+		// Requests waiting for rate limits or retry_after aren't sent when shutting down
+		gr.Add(func() error {
+			<-ctx.Done()
+			return nil
+		}, func(err error) {
+			b.throttle.stop()
+		})
+	}
 	{
 		gr.Add(func() error {
 			for {
 				select {
 				case <-ctx.Done():
 					return nil
-				case message := <-messages:
+				case message := <-b.messages:
 					if err := process(message); err != nil {
 						level.Info(b.logger).Log(
 							"msg", "failed to process message",
 							"err", err,
-							"sender_id", message.Sender.ID,
-							"sender_username", message.Sender.Username,
+							"sender_id", message.From.ID,
+							"sender_username", message.From.Username,
+						)
+					}
+				case callback := <-b.callbacks:
+					if err := processCallback(callback); err != nil {
+						level.Info(b.logger).Log(
+							"msg", "failed to process callback",
+							"err", err,
+							"sender_id", callback.From.ID,
+							"sender_username", callback.From.Username,
 						)
 					}
 				}
@@ -287,16 +611,18 @@ func (b *Bot) Run(ctx context.Context, webhooks <-chan notify.WebhookMessage) er
 	return gr.Run()
 }
 
-// sendWebhook sends messages received via webhook to all subscribed chats
-func (b *Bot) sendWebhook(ctx context.Context, webhooks <-chan notify.WebhookMessage) error {
+// sendWebhook queues messages received via webhook for all subscribed chats of the webhook's route
+func (b *Bot) sendWebhook(ctx context.Context, webhooks <-chan alertmanager.Webhook) error {
 	for {
 		select {
 		case <-ctx.Done():
 			return nil
 		case w := <-webhooks:
-			chats, err := b.chats.List()
+			b.metrics.webhookAlerts.Observe(float64(len(w.Alerts)))
+
+			chats, err := b.routeChats(w.Route)
 			if err != nil {
-				level.Error(b.logger).Log("msg", "failed to get chat list from store", "err", err)
+				level.Error(b.logger).Log("msg", "failed to get chat list for route", "route", w.Route, "err", err)
 				continue
 			}
 
@@ -310,195 +636,847 @@ func (b *Bot) sendWebhook(ctx context.Context, webhooks <-chan notify.WebhookMes
 				ExternalURL:       w.ExternalURL,
 			}
 
-			out, err := b.templates.ExecuteHTMLString(`{{ template "telegram.default" . }}`, data)
-			if err != nil {
-				level.Warn(b.logger).Log("msg", "failed to template alerts", "err", err)
-				continue
-			}
+			// Render the webhook only once for every template and language chats have chosen
+			templates := b.currentTemplates()
+			rendered := map[string]Message{}
 
 			for _, chat := range chats {
 				if !chat.CheckFilters(w.CommonLabels) {
 					level.Debug(b.logger).Log("msg", "ignored by filter")
+					b.metrics.filtered.Inc()
 					continue
 				}
-				err = b.telegram.SendMessage(chat, b.truncateMessage(out), &telebot.SendOptions{ParseMode: telebot.ModeHTML})
-				if err != nil {
-					level.Warn(b.logger).Log("msg", "failed to send message to subscribed chat", "err", err)
+
+				if b.deliveries.unchanged(chat.Recipient(), w.GroupKey, w.Alerts, chat.RepeatInterval, time.Now()) {
+					level.Debug(b.logger).Log("msg", "suppressed repeated notification of unchanged alert group", "chat_id", chat.ID)
+					b.metrics.suppressed.Inc()
+					continue
 				}
+
+				lang := chat.Language
+				if lang == "" {
+					lang = DefaultLanguage
+				}
+
+				key := chat.Template + "/" + lang
+				out, ok := rendered[key]
+				if !ok {
+					out, err = templates.Notification(chat.Template, lang, data)
+					if err != nil {
+						level.Warn(b.logger).Log("msg", "failed to template alerts", "template", chat.Template, "err", err)
+						continue
+					}
+					rendered[key] = out
+				}
+
+				m := out
+				m.Silent = out.Silent || chat.IsSilent(w.Alerts)
+
+				b.deliveries.queued(chat.Recipient(), w.GroupKey, w.Alerts)
+				if !b.queue.push(notification{chat: chat, lang: lang, message: m, webhooks: []alertmanager.Webhook{w}, queued: time.Now()}) {
+					level.Warn(b.logger).Log("msg", "dropped notification, the queue is full", "chat_id", chat.ID)
+					b.metrics.dropped.WithLabelValues("queue_full").Inc()
+					b.deliveries.sent(chat.Recipient(), w.GroupKey, w.Alerts)
+				}
+			}
+		}
+	}
+}
+
+// routeChats returns the subscribed chats a webhook sent to the given route is delivered to.
+// Webhooks without a route are delivered to all subscribed chats.
+func (b *Bot) routeChats(route string) ([]AugmentedChat, error) {
+	chats, err := b.chats.List()
+	if err != nil {
+		return nil, err
+	}
+	if route == "" {
+		return chats, nil
+	}
+
+	var r Route
+	if strings.HasPrefix(route, routeChatPrefix) {
+		id, err := strconv.ParseInt(strings.TrimPrefix(route, routeChatPrefix), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid chat id in route %q", route)
+		}
+		r.AddChat(id)
+	} else {
+		static, ok := b.staticRoute(route)
+		if b.routes == nil && !ok {
+			return nil, fmt.Errorf("named routes are not enabled")
+		}
+		if b.routes != nil {
+			r, err = b.routes.Get(route)
+			if err == store.ErrKeyNotFound && ok {
+				err = nil
 			}
+			if err == store.ErrKeyNotFound {
+				return nil, fmt.Errorf("route %q does not exist", route)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		// Chats can be added to static routes with /route_add too
+		for _, id := range static.ChatIDs {
+			r.AddChat(id)
+		}
+	}
+
+	var routed []AugmentedChat
+	for _, chat := range chats {
+		if r.HasChat(chat.ID) {
+			routed = append(routed, chat)
 		}
 	}
+	if len(routed) == 0 {
+		level.Warn(b.logger).Log("msg", "no subscribed chat for route", "route", route)
+	}
+
+	return routed, nil
 }
 
-func (b *Bot) handleStart(message telebot.Message) {
+func (b *Bot) handleStart(message botapi.Message) {
 	ac := NewAugmentedChat(message)
+	// Keep the settings of a subscribed chat, like its template or pinning, when changing its filters
+	if chat, err := b.chats.Get(ac.Recipient()); err == nil {
+		chat.UserLabelFilters = ac.UserLabelFilters
+		chat.Chat = ac.Chat
+		ac = chat
+	}
+	if ac.Language == "" {
+		ac.Language = language(message.From.LanguageCode)
+	}
+
+	lang := ac.Language
+	if lang == "" {
+		lang = DefaultLanguage
+	}
+
 	if err := b.chats.Add(ac); err != nil {
 		level.Warn(b.logger).Log("msg", "failed to add chat to chat store", "err", err)
-		b.telegram.SendMessage(message.Chat, "I can't add this chat to the subscribers list.", nil)
+		b.reply(messageRecipient(message), tr(lang, "I can't add this chat to the subscribers list."))
 		return
 	}
 
 	filters := ac.GetFiltersAsString()
-	b.telegram.SendMessage(message.Chat, fmt.Sprintf(responseStart, message.Sender.FirstName, filters), nil)
+	b.reply(messageRecipient(message), tr(lang, responseStart, message.From.FirstName, filters))
 	level.Info(b.logger).Log(
 		"user subscribed",
-		"username", message.Sender.Username,
-		"user_id", message.Sender.ID,
+		"username", message.From.Username,
+		"user_id", message.From.ID,
+		"thread_id", ac.ThreadID,
 	)
 }
 
-func (b *Bot) handleStop(message telebot.Message) {
+func (b *Bot) handleStop(message botapi.Message) {
+	lang := b.language(messageRecipient(message), message.From)
+
+	b.unpinAll(messageRecipient(message))
 	if err := b.chats.Remove(NewAugmentedChat(message)); err != nil {
 		level.Warn(b.logger).Log("msg", "failed to remove chat from chat store", "err", err)
-		b.telegram.SendMessage(message.Chat, "I can't remove this chat from the subscribers list.", nil)
+		b.reply(messageRecipient(message), tr(lang, "I can't remove this chat from the subscribers list."))
 		return
 	}
 
-	b.telegram.SendMessage(message.Chat, fmt.Sprintf(responseStop, message.Sender.FirstName), nil)
+	b.reply(messageRecipient(message), tr(lang, responseStop, message.From.FirstName))
 	level.Info(b.logger).Log(
 		"user unsubscribed",
-		"username", message.Sender.Username,
-		"user_id", message.Sender.ID,
+		"username", message.From.Username,
+		"user_id", message.From.ID,
 	)
 }
 
-func (b *Bot) handleHelp(message telebot.Message) {
-	b.telegram.SendMessage(message.Chat, responseHelp, nil)
+func (b *Bot) handleHelp(message botapi.Message) {
+	b.reply(messageRecipient(message), tr(b.language(messageRecipient(message), message.From), responseHelp))
 }
 
-func (b *Bot) handleChats(message telebot.Message) {
+func (b *Bot) handleChats(message botapi.Message) {
+	lang := b.language(messageRecipient(message), message.From)
+
 	chats, err := b.chats.List()
 	if err != nil {
 		level.Warn(b.logger).Log("msg", "failed to list chats from chat store", "err", err)
-		b.telegram.SendMessage(message.Chat, "I can't list the subscribed chats.", nil)
+		b.reply(messageRecipient(message), tr(lang, "I can't list the subscribed chats."))
 		return
 	}
 
-	list := ""
-	var chatname string
-	for idx, chat := range chats {
-		if chat.IsGroupChat() {
-			chatname = chat.Title
-		} else {
-			chatname = chat.Username
-		}
-		list = list + fmt.Sprintf("[%d] @%s - %s\n\n", idx+1, chatname, chat.GetFiltersAsString())
-	}
-
-	b.telegram.SendMessage(message.Chat, "Currently these chat have subscribed:\n\n"+list, nil)
+	b.sendReply(messageRecipient(message), lang, TemplateChats, newChatsData(lang, chats))
 }
 
-func (b *Bot) handleFilters(message telebot.Message) {
+func (b *Bot) handleFilters(message botapi.Message) {
+	lang := b.language(messageRecipient(message), message.From)
+
 	var filters string
 	chats, err := b.chats.List()
 	if err == nil {
 		for _, chat := range chats {
-			if chat.ID == message.Chat.ID {
-				filters = "Currently applied filters:\n" + chat.GetFiltersAsString()
+			if chat.Recipient() == messageRecipient(message) {
+				filters = tr(lang, "Currently applied filters:\n") + chat.GetFiltersAsString()
 				break
 			}
 		}
 	} else {
-		filters = "I can't get current filters."
+		filters = tr(lang, "I can't get current filters.")
 	}
 
-	b.telegram.SendMessage(message.Chat, filters+"\n"+responseFilters, nil)
+	b.reply(messageRecipient(message), filters+"\n"+tr(lang, responseFilters))
 }
 
-func (b *Bot) handleStatus(message telebot.Message) {
-	s, err := alertmanager.Status(b.logger, b.alertmanager.String())
+func (b *Bot) handleTemplate(message botapi.Message) {
+	lang := b.language(messageRecipient(message), message.From)
+
+	chat, err := b.chats.Get(messageRecipient(message))
+	if err == store.ErrKeyNotFound {
+		b.reply(messageRecipient(message), tr(lang, "This chat isn't subscribed, please %s first.", commandStart))
+		return
+	}
 	if err != nil {
-		level.Warn(b.logger).Log("msg", "failed to get status", "err", err)
-		b.telegram.SendMessage(message.Chat, fmt.Sprintf("failed to get status... %v", err), nil)
+		level.Warn(b.logger).Log("msg", "failed to get chat from chat store", "err", err)
+		b.reply(messageRecipient(message), tr(lang, "I can't get this chat's template."))
 		return
 	}
 
-	uptime := durafmt.Parse(time.Since(s.Data.Uptime))
-	uptimeBot := durafmt.Parse(time.Since(b.startTime))
+	templates := b.currentTemplates()
 
-	b.telegram.SendMessage(
-		message.Chat,
-		fmt.Sprintf(
-			"*AlertManager*\nVersion: %s\nUptime: %s\n*AlertManager Bot*\nVersion: %s\nUptime: %s",
-			s.Data.VersionInfo.Version,
-			uptime,
-			b.revision,
-			uptimeBot,
-		),
-		&telebot.SendOptions{ParseMode: telebot.ModeMarkdown},
-	)
+	args := strings.Fields(message.Text)[1:]
+	if len(args) == 0 {
+		current := chat.Template
+		if current == "" {
+			current = DefaultTemplate
+		}
+		b.reply(messageRecipient(message), tr(lang,
+			"This chat uses the %s template.\nAvailable templates: %s\n\nChoose one with %s name",
+			current, strings.Join(templates.Names(), ", "), commandTemplate,
+		))
+		return
+	}
+
+	name := args[0]
+	if !templates.Has(name) {
+		b.reply(messageRecipient(message), tr(lang,
+			"There's no template %s.\nAvailable templates: %s",
+			name, strings.Join(templates.Names(), ", "),
+		))
+		return
+	}
+
+	chat.Template = name
+	if name == DefaultTemplate {
+		chat.Template = ""
+	}
+	if err := b.chats.Add(chat); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to update chat in chat store", "err", err)
+		b.reply(messageRecipient(message), tr(lang, "I can't change this chat's template."))
+		return
+	}
+
+	b.reply(messageRecipient(message), tr(lang, "Alerts are now sent with the %s template.", name))
 }
 
-func (b *Bot) handleAlerts(message telebot.Message) {
-	alerts, err := alertmanager.ListAlerts(b.logger, b.alertmanager.String())
+// handleLang shows or chooses the language of the chat's replies and alerts
+func (b *Bot) handleLang(message botapi.Message) {
+	lang := b.language(messageRecipient(message), message.From)
+
+	chat, err := b.chats.Get(messageRecipient(message))
+	if err == store.ErrKeyNotFound {
+		b.reply(messageRecipient(message), tr(lang, "This chat isn't subscribed, please %s first.", commandStart))
+		return
+	}
 	if err != nil {
-		b.telegram.SendMessage(message.Chat, fmt.Sprintf("failed to list alerts... %v", err), nil)
+		level.Warn(b.logger).Log("msg", "failed to get chat from chat store", "err", err)
+		b.reply(messageRecipient(message), tr(lang, "I can't get this chat's language."))
+		return
+	}
+
+	args := strings.Fields(message.Text)[1:]
+	if len(args) == 0 {
+		b.reply(messageRecipient(message), tr(lang,
+			"This chat's language is %s.\nAvailable languages: %s\n\nChoose one with %s language",
+			lang, strings.Join(languages(), ", "), commandLang,
+		))
 		return
 	}
 
+	chat.Language = language(args[0])
+	if chat.Language == "" {
+		b.reply(messageRecipient(message), tr(lang,
+			"There's no language %s.\nAvailable languages: %s",
+			args[0], strings.Join(languages(), ", "),
+		))
+		return
+	}
+	if err := b.chats.Add(chat); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to update chat in chat store", "err", err)
+		b.reply(messageRecipient(message), tr(lang, "I can't change this chat's language."))
+		return
+	}
+
+	b.reply(messageRecipient(message), tr(chat.Language, "I'll reply in English now."))
+}
+
+// handleSilent shows or chooses the matchers of alerts sent to the chat without notification sound
+func (b *Bot) handleSilent(message botapi.Message) {
+	lang := b.language(messageRecipient(message), message.From)
+
+	chat, err := b.chats.Get(messageRecipient(message))
+	if err == store.ErrKeyNotFound {
+		b.reply(messageRecipient(message), tr(lang, "This chat isn't subscribed, please %s first.", commandStart))
+		return
+	}
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to get chat from chat store", "err", err)
+		b.reply(messageRecipient(message), tr(lang, "I can't get this chat's silent alerts."))
+		return
+	}
+
+	args := strings.Fields(message.Text)[1:]
+	if len(args) == 0 {
+		if len(chat.SilentMatchers) == 0 {
+			b.reply(messageRecipient(message), tr(lang,
+				"All alerts are sent with sound.\n\nSend some silently with %s matchers, like %s severity!=critical",
+				commandSilent, commandSilent,
+			))
+			return
+		}
+		b.reply(messageRecipient(message), tr(lang,
+			"Alerts matching %s are sent silently.\n\nChange them with %s matchers or send all with sound with %s off",
+			strings.Join(chat.SilentMatchers, " "), commandSilent, commandSilent,
+		))
+		return
+	}
+
+	var reply string
+	if len(args) == 1 && args[0] == "off" {
+		chat.SilentMatchers = nil
+		reply = tr(lang, "All alerts are sent with sound now.")
+	} else {
+		matchers, err := alertmanager.ParseMatchers(args)
+		if err == nil {
+			// regular expressions are only compiled when matching
+			_, err = alertmanager.MatchLabels(matchers, nil)
+		}
+		if err != nil {
+			b.reply(messageRecipient(message), err.Error())
+			return
+		}
+		chat.SilentMatchers = matchers
+		reply = tr(lang, "Alerts matching %s are sent silently now.", strings.Join(matchers, " "))
+	}
+
+	if err := b.chats.Add(chat); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to update chat in chat store", "err", err)
+		b.reply(messageRecipient(message), tr(lang, "I can't change this chat's silent alerts."))
+		return
+	}
+
+	b.reply(messageRecipient(message), reply)
+}
+
+// handlePin shows or chooses if the messages of critical alert groups are pinned in the chat
+func (b *Bot) handlePin(message botapi.Message) {
+	lang := b.language(messageRecipient(message), message.From)
+
+	if b.pins == nil {
+		b.reply(messageRecipient(message), tr(lang, "Pinning alerts is not enabled."))
+		return
+	}
+
+	chat, err := b.chats.Get(messageRecipient(message))
+	if err == store.ErrKeyNotFound {
+		b.reply(messageRecipient(message), tr(lang, "This chat isn't subscribed, please %s first.", commandStart))
+		return
+	}
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to get chat from chat store", "err", err)
+		b.reply(messageRecipient(message), tr(lang, "I can't get if this chat pins critical alerts."))
+		return
+	}
+
+	args := strings.Fields(message.Text)[1:]
+	if len(args) == 0 {
+		if chat.PinCritical {
+			b.reply(messageRecipient(message), tr(lang, "Critical alerts are pinned until they're resolved.\n\nStop pinning them with %s off", commandPin))
+		} else {
+			b.reply(messageRecipient(message), tr(lang, "Critical alerts aren't pinned.\n\nPin them until they're resolved with %s on", commandPin))
+		}
+		return
+	}
+
+	switch args[0] {
+	case "on":
+		chat.PinCritical = true
+	case "off":
+		chat.PinCritical = false
+		b.unpinAll(chat.Recipient())
+	default:
+		b.reply(messageRecipient(message), tr(lang, "Please choose %s on or %s off.", commandPin, commandPin))
+		return
+	}
+
+	if err := b.chats.Add(chat); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to update chat in chat store", "err", err)
+		b.reply(messageRecipient(message), tr(lang, "I can't change if this chat pins critical alerts."))
+		return
+	}
+
+	if chat.PinCritical {
+		b.reply(messageRecipient(message), tr(lang, "Critical alerts are pinned now, I need to be an admin allowed to pin messages."))
+	} else {
+		b.reply(messageRecipient(message), tr(lang, "Critical alerts aren't pinned anymore."))
+	}
+}
+
+// handleRepeat shows or chooses the interval repeated notifications of unchanged alert groups are suppressed for
+func (b *Bot) handleRepeat(message botapi.Message) {
+	lang := b.language(messageRecipient(message), message.From)
+
+	chat, err := b.chats.Get(messageRecipient(message))
+	if err == store.ErrKeyNotFound {
+		b.reply(messageRecipient(message), tr(lang, "This chat isn't subscribed, please %s first.", commandStart))
+		return
+	}
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to get chat from chat store", "err", err)
+		b.reply(messageRecipient(message), tr(lang, "I can't get this chat's repeat interval."))
+		return
+	}
+
+	args := strings.Fields(message.Text)[1:]
+	if len(args) == 0 {
+		if chat.RepeatInterval == 0 {
+			b.reply(messageRecipient(message), tr(lang,
+				"Every notification of Alertmanager is sent.\n\nOnly send unchanged alerts again after an interval with %s interval, like %s 4h",
+				commandRepeat, commandRepeat,
+			))
+			return
+		}
+		b.reply(messageRecipient(message), tr(lang,
+			"Unchanged alerts are sent again after %s.\n\nChange it with %s interval or send every notification with %s off",
+			model.Duration(chat.RepeatInterval), commandRepeat, commandRepeat,
+		))
+		return
+	}
+
+	var reply string
+	if args[0] == "off" {
+		chat.RepeatInterval = 0
+		reply = tr(lang, "Every notification of Alertmanager is sent now.")
+	} else {
+		interval, err := model.ParseDuration(args[0])
+		if err != nil || interval <= 0 {
+			b.reply(messageRecipient(message), tr(lang, "%q is not a valid interval, like 30m, 4h or 1d.", args[0]))
+			return
+		}
+		chat.RepeatInterval = time.Duration(interval)
+		reply = tr(lang, "Unchanged alerts are sent again after %s now.", interval)
+	}
+
+	if err := b.chats.Add(chat); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to update chat in chat store", "err", err)
+		b.reply(messageRecipient(message), tr(lang, "I can't change this chat's repeat interval."))
+		return
+	}
+
+	b.reply(messageRecipient(message), reply)
+}
+
+// handleTemplateTest renders sample alerts and the current alerts with a template,
+// so mistakes show before a real alert is sent with it.
+func (b *Bot) handleTemplateTest(message botapi.Message) {
+	templates := b.currentTemplates()
+	lang := b.language(messageRecipient(message), message.From)
+
+	name := b.chatTemplate(messageRecipient(message))
+	if args := strings.Fields(message.Text)[1:]; len(args) > 0 {
+		name = args[0]
+	}
+	if !templates.Has(name) {
+		b.reply(messageRecipient(message), tr(lang,
+			"There's no template %s.\nAvailable templates: %s",
+			name, strings.Join(templates.Names(), ", "),
+		))
+		return
+	}
+
+	out, err := templates.Notification(name, lang, templates.SampleData())
+	if err != nil {
+		b.reply(messageRecipient(message), tr(lang, "failed to render sample alerts with %s template... %v", name, err))
+		return
+	}
+	b.reply(messageRecipient(message), tr(lang, "Sample alerts rendered with %s template:", name))
+	if _, err := b.sendMessage(messageRecipient(message), lang, out); err != nil {
+		b.reply(messageRecipient(message), tr(lang, "failed to send sample alerts... %v", err))
+		return
+	}
+
+	alerts, err := alertmanager.ListAlerts(b.logger, b.alertmanagerClient, b.alertmanager.String(), "")
+	if err != nil {
+		b.reply(messageRecipient(message), tr(lang, "failed to list alerts... %v", err))
+		return
+	}
 	if len(alerts) == 0 {
-		b.telegram.SendMessage(message.Chat, "No alerts right now! 🎉", nil)
+		b.reply(messageRecipient(message), tr(lang, "No alerts right now to render! 🎉"))
 		return
 	}
 
-	out, err := b.tmplAlerts(alerts...)
+	out, err = templates.Notification(name, lang, templates.Data("default", nil, alerts...))
 	if err != nil {
+		b.reply(messageRecipient(message), tr(lang, "failed to render current alerts with %s template... %v", name, err))
 		return
 	}
+	b.reply(messageRecipient(message), tr(lang, "Current alerts rendered with %s template:", name))
+	if _, err := b.sendMessage(messageRecipient(message), lang, out); err != nil {
+		b.reply(messageRecipient(message), tr(lang, "failed to send current alerts... %v", err))
+	}
+}
+
+func (b *Bot) handleStatus(message botapi.Message) {
+	lang := b.language(messageRecipient(message), message.From)
 
-	err = b.telegram.SendMessage(message.Chat, b.truncateMessage(out), &telebot.SendOptions{
-		ParseMode: telebot.ModeHTML,
+	s, err := alertmanager.Status(b.logger, b.alertmanagerClient, b.alertmanager.String())
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to get status", "err", err)
+		b.reply(messageRecipient(message), tr(lang, "failed to get status... %v", err))
+		return
+	}
+
+	b.sendReply(messageRecipient(message), lang, TemplateStatus, StatusData{
+		AlertmanagerVersion: s.Data.VersionInfo.Version,
+		AlertmanagerStarted: s.Data.Uptime,
+		BotVersion:          b.revision,
+		BotStarted:          b.startTime,
+		Language:            lang,
 	})
+}
+
+func (b *Bot) handleAlerts(message botapi.Message) {
+	lang := b.language(messageRecipient(message), message.From)
+
+	filter, err := alertmanager.AlertsFilter(strings.Fields(message.Text)[1:])
+	if err != nil {
+		b.reply(messageRecipient(message), err.Error())
+		return
+	}
+
+	alerts, err := alertmanager.ListAlerts(b.logger, b.alertmanagerClient, b.alertmanager.String(), filter)
+	if err != nil {
+		b.reply(messageRecipient(message), tr(lang, "failed to list alerts... %v", err))
+		return
+	}
+
+	if b.attachmentSize > 0 {
+		attached, err := b.sendAlertsAttachment(messageRecipient(message), lang, alerts)
+		if err != nil {
+			level.Warn(b.logger).Log("msg", "failed to send alerts attachment", "err", err)
+			b.reply(messageRecipient(message), tr(lang, "failed to list alerts... %v", err))
+			return
+		}
+		if attached {
+			return
+		}
+	}
+
+	text, keyboard, err := b.alertsPage(messageRecipient(message), lang, filter, alerts, 0)
 	if err != nil {
+		b.reply(messageRecipient(message), tr(lang, "failed to list alerts... %v", err))
+		return
+	}
+
+	if err := b.sendKeyboard(messageRecipient(message), text, keyboard); err != nil {
 		level.Warn(b.logger).Log("msg", "failed to send message", "err", err)
 	}
 }
 
-func (b *Bot) handleSilences(message telebot.Message) {
-	silences, err := alertmanager.ListSilences(b.logger, b.alertmanager.String())
+// sendAlertsAttachment sends the alerts as a file captioned with a short summary in the language,
+// if they are longer than the configured attachment size. It returns whether they were sent.
+func (b *Bot) sendAlertsAttachment(recipient Recipient, lang string, alerts []*types.Alert) (bool, error) {
+	out, err := b.tmplAlerts(recipient, lang, alerts...)
+	if err != nil {
+		return false, err
+	}
+
+	if messageLength(out) <= b.attachmentSize {
+		return false, nil
+	}
+
+	data := b.currentTemplates().Data("default", nil, alerts...)
+
+	name, content, err := renderAttachment(b.attachmentFormat, lang, data, out)
 	if err != nil {
-		b.telegram.SendMessage(message.Chat, fmt.Sprintf("failed to list silences... %v", err), nil)
+		return false, err
+	}
+
+	_, err = b.sendDocument(recipient, name, content, alertsSummary(lang, data.Alerts)+" — "+tr(lang, "see attached"), false)
+	return true, err
+}
+
+func (b *Bot) handleSilences(message botapi.Message) {
+	lang := b.language(messageRecipient(message), message.From)
+
+	silences, err := alertmanager.ListSilences(b.logger, b.alertmanagerClient, b.alertmanager.String())
+	if err != nil {
+		b.reply(messageRecipient(message), tr(lang, "failed to list silences... %v", err))
+		return
+	}
+
+	b.sendReply(messageRecipient(message), lang, TemplateSilences, newSilencesData(lang, silences))
+}
+
+func (b *Bot) handleRoutes(message botapi.Message) {
+	lang := b.language(messageRecipient(message), message.From)
+
+	static := b.listStaticRoutes()
+	if b.routes == nil && len(static) == 0 {
+		b.reply(messageRecipient(message), tr(lang, "Named routes are not enabled."))
+		return
+	}
+
+	var routes []Route
+	if b.routes != nil {
+		var err error
+		routes, err = b.routes.List()
+		if err != nil {
+			level.Warn(b.logger).Log("msg", "failed to list routes from route store", "err", err)
+			b.reply(messageRecipient(message), tr(lang, "I can't list the webhook routes."))
+			return
+		}
+	}
+
+	format := func(r Route) string {
+		ids := make([]string, 0, len(r.ChatIDs))
+		for _, id := range r.ChatIDs {
+			ids = append(ids, strconv.FormatInt(id, 10))
+		}
+		return fmt.Sprintf("%s%s - %s", alertmanager.WebhookRoutePrefix, r.Name, strings.Join(ids, ", "))
+	}
+
+	list := ""
+	for _, r := range static {
+		list = list + format(r) + tr(lang, " (config file)") + "\n"
+	}
+	for _, r := range routes {
+		list = list + format(r) + "\n"
+	}
+	if list == "" {
+		list = tr(lang, "No named routes yet.\n")
+	}
+
+	b.reply(messageRecipient(message), tr(lang,
+		"Currently these webhook routes exist:\n\n%s\nEvery subscribed chat can also be addressed with %s%s<chat_id>.",
+		list, alertmanager.WebhookRoutePrefix, routeChatPrefix,
+	))
+}
+
+func (b *Bot) handleRouteAdd(message botapi.Message) {
+	b.updateRoute(message, func(r *Route, id int64, lang string) string {
+		r.AddChat(id)
+		return tr(lang, "Webhooks for %s%s are now sent to chat %d.", alertmanager.WebhookRoutePrefix, r.Name, id)
+	})
+}
+
+func (b *Bot) handleRouteDel(message botapi.Message) {
+	b.updateRoute(message, func(r *Route, id int64, lang string) string {
+		r.RemoveChat(id)
+		if static, ok := b.staticRoute(r.Name); ok && static.HasChat(id) {
+			return tr(lang, "Chat %d is part of %s%s in the config file and keeps receiving its webhooks.", id, alertmanager.WebhookRoutePrefix, r.Name)
+		}
+		return tr(lang, "Webhooks for %s%s are no longer sent to chat %d.", alertmanager.WebhookRoutePrefix, r.Name, id)
+	})
+}
+
+// updateRoute parses the route name and optional chat ID of a route command,
+// defaulting to the chat the command was sent in, and stores the route changed by update.
+// update returns the reply in the given language.
+func (b *Bot) updateRoute(message botapi.Message, update func(r *Route, id int64, lang string) string) {
+	lang := b.language(messageRecipient(message), message.From)
+
+	if b.routes == nil {
+		b.reply(messageRecipient(message), tr(lang, "Named routes are not enabled."))
+		return
+	}
+
+	args := strings.Fields(message.Text)[1:]
+	if len(args) == 0 || len(args) > 2 {
+		b.reply(messageRecipient(message), tr(lang, "Please provide a route name and optionally a chat id."))
 		return
 	}
 
-	if len(silences) == 0 {
-		b.telegram.SendMessage(message.Chat, "No silences right now.", nil)
+	name := args[0]
+	if err := ValidRouteName(name); err != nil {
+		b.reply(messageRecipient(message), err.Error())
 		return
 	}
 
-	var out string
-	for _, silence := range silences {
-		out = out + alertmanager.SilenceMessage(silence) + "\n"
+	id := message.Chat.ID
+	if len(args) == 2 {
+		var err error
+		id, err = strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			b.reply(messageRecipient(message), tr(lang, "%q is not a valid chat id.", args[1]))
+			return
+		}
 	}
 
-	b.telegram.SendMessage(message.Chat, out, &telebot.SendOptions{ParseMode: telebot.ModeMarkdown})
+	r, err := b.routes.Get(name)
+	if err == store.ErrKeyNotFound {
+		r, err = Route{Name: name}, nil
+	}
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to get route from route store", "err", err)
+		b.reply(messageRecipient(message), tr(lang, "I can't read the webhook route."))
+		return
+	}
+
+	reply := update(&r, id, lang)
+
+	if len(r.ChatIDs) == 0 {
+		err = b.routes.Remove(r)
+		if err == store.ErrKeyNotFound {
+			err = nil
+		}
+	} else {
+		err = b.routes.Add(r)
+	}
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to update route in route store", "err", err)
+		b.reply(messageRecipient(message), tr(lang, "I can't update the webhook route."))
+		return
+	}
+
+	b.reply(messageRecipient(message), reply)
+	level.Info(b.logger).Log(
+		"msg", "webhook route updated",
+		"route", r.Name,
+		"chats", len(r.ChatIDs),
+		"user_id", message.From.ID,
+	)
+}
+
+// tmplAlerts renders the alerts in the language with the notification template of the recipient
+func (b *Bot) tmplAlerts(recipient Recipient, lang string, alerts ...*types.Alert) (string, error) {
+	return b.currentTemplates().Alerts(b.chatTemplate(recipient), lang, alerts...)
+}
+
+// chatTemplate returns the name of the notification template the chat or forum topic has chosen
+func (b *Bot) chatTemplate(recipient Recipient) string {
+	chat, err := b.chats.Get(recipient)
+	if err != nil {
+		if err != store.ErrKeyNotFound {
+			level.Warn(b.logger).Log("msg", "failed to get chat from chat store", "err", err)
+		}
+		return DefaultTemplate
+	}
+	if chat.Template == "" {
+		return DefaultTemplate
+	}
+	return chat.Template
 }
 
-func (b *Bot) tmplAlerts(alerts ...*types.Alert) (string, error) {
-	data := b.templates.Data("default", nil, alerts...)
+// language returns the language the chat or forum topic has chosen, or else the language of the sender's Telegram app,
+// if there are translations for it, or else the default language.
+func (b *Bot) language(recipient Recipient, sender botapi.User) string {
+	chat, err := b.chats.Get(recipient)
+	if err != nil && err != store.ErrKeyNotFound {
+		level.Warn(b.logger).Log("msg", "failed to get chat from chat store", "err", err)
+	}
+	if err == nil && chat.Language != "" {
+		return chat.Language
+	}
+	if lang := language(sender.LanguageCode); lang != "" {
+		return lang
+	}
+	return DefaultLanguage
+}
 
-	out, err := b.templates.ExecuteHTMLString(`{{ template "telegram.default" . }}`, data)
+// sendReply renders the data with the reply template and sends it, errors are sent in the language
+func (b *Bot) sendReply(recipient Recipient, lang string, name string, data interface{}) {
+	reply, err := b.currentTemplates().Reply(name, data)
 	if err != nil {
-		return "", err
+		level.Warn(b.logger).Log("msg", "failed to template reply", "template", name, "err", err)
+		b.reply(recipient, tr(lang, "failed to template reply... %v", err))
+		return
 	}
 
-	return out, nil
+	if _, err := b.sendMessage(recipient, lang, reply); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to send message", "err", err)
+	}
 }
 
-// Truncate very big message
-func (b *Bot) truncateMessage(str string) string {
-	truncateMsg := str
-	if len(str) > 4095 { // telegram API can only support 4096 bytes per message
-		level.Warn(b.logger).Log("msg", "Message is bigger than 4095, truncate...")
-		// find the end of last alert, we do not want break the html tags
-		i := strings.LastIndex(str[0:4080], "\n\n") // 4080 + "\n<b>[SNIP]</b>" == 4095
-		if i > 1 {
-			truncateMsg = str[0:i] + "\n<b>[SNIP]</b>"
-		} else {
-			truncateMsg = "Message is too long... can't send.."
-			level.Warn(b.logger).Log("msg", "truncateMessage: Unable to find the end of last alert.")
+// sendMessage sends a message in its parse mode, split into numbered parts if it's too long for a single message.
+// Messages that would need more than maxMessages parts are sent as a text file attachment instead.
+// Parts Telegram can't parse the formatting of are sent again as plain text.
+// The notice announcing an attachment is sent in the language.
+// It returns the ID of the first message sent.
+func (b *Bot) sendMessage(recipient Recipient, lang string, m Message) (int, error) {
+	mode := m.ParseMode
+	parts := splitText(m.Text, mode, maxMessageLength-partHeaderLength)
+
+	if b.maxMessages > 0 && len(parts) > b.maxMessages {
+		level.Debug(b.logger).Log("msg", "message too long, sending as file", "parts", len(parts))
+		return b.sendDocument(
+			recipient, "alerts.txt", []byte(toPlainText(m.Text, mode)),
+			tr(lang, "This message is too long for %d messages, it's attached as a file.", b.maxMessages),
+			m.Silent,
+		)
+	}
+
+	var first int
+	for _, part := range numberParts(parts, mode) {
+		params := botapi.SendMessageParams{
+			ChatID:              recipient.ChatID,
+			MessageThreadID:     recipient.ThreadID,
+			Text:                part,
+			ParseMode:           apiParseMode(mode),
+			DisableNotification: m.Silent,
+		}
+		var sent botapi.Message
+		send := func() (err error) {
+			sent, err = b.telegram.SendMessage(params)
+			return err
+		}
+
+		err := b.throttle.do(recipient.ChatID, send)
+		if isEntitiesError(err) {
+			level.Warn(b.logger).Log("msg", "telegram can't parse the message, sending it as plain text", "parseMode", mode, "err", err)
+			params.ParseMode = botapi.ModeDefault
+			// the plain text can be longer than the part, like with the URLs of links
+			for _, plain := range splitText(toPlainText(part, mode), ParseModePlain, maxMessageLength) {
+				params.Text = plain
+				if err = b.throttle.do(recipient.ChatID, send); err != nil {
+					break
+				}
+				if first == 0 {
+					first = sent.ID
+				}
+			}
+		}
+		if err != nil {
+			return first, err
+		}
+		if first == 0 {
+			first = sent.ID
 		}
-		return truncateMsg
 	}
-	return truncateMsg
+
+	return first, nil
+}
+
+// sendDocument sends content as a file attachment with the given file name, captioned by a short message.
+// Silent documents are sent without notification sound. It returns the ID of the document's message.
+func (b *Bot) sendDocument(recipient Recipient, name string, content []byte, caption string, silent bool) (int, error) {
+	var sent botapi.Message
+	err := b.throttle.do(recipient.ChatID, func() (err error) {
+		sent, err = b.telegram.SendDocument(botapi.SendDocumentParams{
+			ChatID:              recipient.ChatID,
+			MessageThreadID:     recipient.ThreadID,
+			Name:                name,
+			Content:             content,
+			Caption:             caption,
+			DisableNotification: silent,
+		})
+		return err
+	})
+	return sent.ID, err
 }
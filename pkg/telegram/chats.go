@@ -4,9 +4,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/docker/libkv/store"
-	"github.com/tucnak/telebot"
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/metalmatze/alertmanager-bot/pkg/botapi"
+	"github.com/prometheus/alertmanager/template"
 )
 
 const telegramChatsDirectory = "telegram/chats"
@@ -16,14 +19,48 @@ type ChatStore struct {
 	kv store.Store
 }
 
-// AugmentedChat - telebot.Chat with user options to filter alerts by labels
+// AugmentedChat - botapi.Chat with user options to filter alerts by labels
 type AugmentedChat struct {
 	UserLabelFilters map[string]map[string]struct{}
-	telebot.Chat
+	// Template is the name of the notification template, empty for the default template
+	Template string `json:",omitempty"`
+	// Language of the replies and alerts, empty for the language of the user's Telegram app
+	Language string `json:",omitempty"`
+	// ThreadID is the forum topic that subscribed, zero if the whole chat subscribed
+	ThreadID int `json:",omitempty"`
+	// SilentMatchers send alerts matching all of them without notification sound, like severity!="critical"
+	SilentMatchers []string `json:",omitempty"`
+	// PinCritical pins the messages of firing critical alert groups until they're resolved
+	PinCritical bool `json:",omitempty"`
+	// RepeatInterval suppresses repeated notifications of alert groups that didn't change for as long
+	RepeatInterval time.Duration `json:",omitempty"`
+	botapi.Chat
 }
 
-// NewAugmentedChat parse telebot.Message.Text field to get label filters
-func NewAugmentedChat(message telebot.Message) AugmentedChat {
+// Recipient is a chat or a forum topic of a supergroup messages are sent to
+type Recipient struct {
+	ChatID int64
+	// ThreadID is the forum topic, zero for the chat itself or the General topic of forums
+	ThreadID int
+}
+
+// messageRecipient returns the chat or forum topic the message was sent in, so replies are sent there too
+func messageRecipient(message botapi.Message) Recipient {
+	r := Recipient{ChatID: message.Chat.ID}
+	// Replies in groups have a thread ID too, only forum topics are threads of their own
+	if message.IsTopicMessage {
+		r.ThreadID = message.MessageThreadID
+	}
+	return r
+}
+
+// Recipient returns the chat or the forum topic that subscribed
+func (c AugmentedChat) Recipient() Recipient {
+	return Recipient{ChatID: c.ID, ThreadID: c.ThreadID}
+}
+
+// NewAugmentedChat parse botapi.Message.Text field to get label filters
+func NewAugmentedChat(message botapi.Message) AugmentedChat {
 	// First field is the command, like '/start', just skip it
 	payload := strings.Fields(message.Text)[1:]
 	userLabelFilters := make(map[string]map[string]struct{})
@@ -37,7 +74,11 @@ func NewAugmentedChat(message telebot.Message) AugmentedChat {
 			userLabelFilters[data[0]] = set
 		}
 	}
-	return AugmentedChat{userLabelFilters, message.Chat}
+	return AugmentedChat{
+		UserLabelFilters: userLabelFilters,
+		ThreadID:         messageRecipient(message).ThreadID,
+		Chat:             message.Chat,
+	}
 }
 
 func (c *AugmentedChat) GetFiltersAsString() string {
@@ -82,6 +123,20 @@ func (c *AugmentedChat) CheckFilters(labels map[string]string) bool {
 	return true
 }
 
+// IsSilent returns true if all alerts match the chat's silent matchers, so they're sent without sound
+func (c *AugmentedChat) IsSilent(alerts template.Alerts) bool {
+	if len(c.SilentMatchers) == 0 {
+		return false
+	}
+	for _, a := range alerts {
+		// the matchers were checked when they were set
+		if ok, err := alertmanager.MatchLabels(c.SilentMatchers, a.Labels); !ok || err != nil {
+			return false
+		}
+	}
+	return true
+}
+
 // NewChatStore stores telegram chats in the provided kv backend
 func NewChatStore(kv store.Store) (*ChatStore, error) {
 	return &ChatStore{kv: kv}, nil
@@ -106,6 +161,29 @@ func (s *ChatStore) List() ([]AugmentedChat, error) {
 	return chats, nil
 }
 
+// chatKey returns the key of the subscription of a chat or forum topic,
+// the whole chat's key is just its ID like before topics were supported
+func chatKey(r Recipient) string {
+	if r.ThreadID == 0 {
+		return fmt.Sprintf("%s/%d", telegramChatsDirectory, r.ChatID)
+	}
+	return fmt.Sprintf("%s/%d:%d", telegramChatsDirectory, r.ChatID, r.ThreadID)
+}
+
+// Get the subscription of a telegram chat or forum topic from the kv backend.
+// If there's no such chat store.ErrKeyNotFound is returned.
+func (s *ChatStore) Get(r Recipient) (AugmentedChat, error) {
+	var c AugmentedChat
+
+	kv, err := s.kv.Get(chatKey(r))
+	if err != nil {
+		return c, err
+	}
+
+	err = json.Unmarshal(kv.Value, &c)
+	return c, err
+}
+
 // Add a telegram chat to the kv backend
 func (s *ChatStore) Add(c AugmentedChat) error {
 	b, err := json.Marshal(c)
@@ -113,13 +191,10 @@ func (s *ChatStore) Add(c AugmentedChat) error {
 		return err
 	}
 
-	key := fmt.Sprintf("%s/%d", telegramChatsDirectory, c.ID)
-
-	return s.kv.Put(key, b, nil)
+	return s.kv.Put(chatKey(c.Recipient()), b, nil)
 }
 
 // Remove a telegram chat from the kv backend
 func (s *ChatStore) Remove(c AugmentedChat) error {
-	key := fmt.Sprintf("%s/%d", telegramChatsDirectory, c.ID)
-	return s.kv.Delete(key)
+	return s.kv.Delete(chatKey(c.Recipient()))
 }
@@ -0,0 +1,146 @@
+package telegram
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/docker/libkv/store"
+	"github.com/go-kit/kit/log"
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/metalmatze/alertmanager-bot/pkg/botapi"
+	"github.com/metalmatze/alertmanager-bot/pkg/botapi/botapitest"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryChats is a BotChatStore keeping the chats in memory
+type memoryChats map[Recipient]AugmentedChat
+
+func (m memoryChats) List() ([]AugmentedChat, error) {
+	var chats []AugmentedChat
+	for _, c := range m {
+		chats = append(chats, c)
+	}
+	return chats, nil
+}
+
+func (m memoryChats) Get(r Recipient) (AugmentedChat, error) {
+	c, ok := m[r]
+	if !ok {
+		return c, store.ErrKeyNotFound
+	}
+	return c, nil
+}
+
+func (m memoryChats) Add(c AugmentedChat) error {
+	m[c.Recipient()] = c
+	return nil
+}
+
+func (m memoryChats) Remove(c AugmentedChat) error {
+	delete(m, c.Recipient())
+	return nil
+}
+
+func TestMessageRecipient(t *testing.T) {
+	chat := botapi.Chat{ID: -100123, Type: botapi.ChatSupergroup, IsForum: true}
+
+	assert.Equal(t, Recipient{ChatID: -100123}, messageRecipient(botapi.Message{Chat: chat}))
+	assert.Equal(t, Recipient{ChatID: -100123, ThreadID: 5}, messageRecipient(botapi.Message{Chat: chat, MessageThreadID: 5, IsTopicMessage: true}))
+	// a reply outside of forum topics
+	assert.Equal(t, Recipient{ChatID: -100123}, messageRecipient(botapi.Message{Chat: chat, MessageThreadID: 7}))
+}
+
+func TestChatKey(t *testing.T) {
+	assert.Equal(t, "telegram/chats/-100123", chatKey(Recipient{ChatID: -100123}))
+	assert.Equal(t, "telegram/chats/-100123:5", chatKey(Recipient{ChatID: -100123, ThreadID: 5}))
+}
+
+func TestIsSilent(t *testing.T) {
+	warning := template.Alert{Labels: template.KV{"severity": "warning"}}
+	critical := template.Alert{Labels: template.KV{"severity": "critical"}}
+
+	chat := AugmentedChat{}
+	assert.False(t, chat.IsSilent(template.Alerts{warning}))
+
+	chat.SilentMatchers = []string{`severity!="critical"`}
+	assert.True(t, chat.IsSilent(template.Alerts{warning}))
+	assert.False(t, chat.IsSilent(template.Alerts{warning, critical}))
+}
+
+func TestSendWebhookToTopics(t *testing.T) {
+	s := botapitest.NewServer()
+	defer s.Close()
+
+	tmpl, err := NewTemplates(&url.URL{}, "../../default.tmpl")
+	assert.NoError(t, err)
+
+	chats := memoryChats{}
+	b := &Bot{
+		logger:    log.NewNopLogger(),
+		telegram:  s.Client(),
+		chats:     chats,
+		templates: tmpl,
+		metrics:   testMetrics(t),
+		queue:     newNotificationQueue(0, prometheus.NewGauge(prometheus.GaugeOpts{Name: "queued"})),
+	}
+
+	chat := botapi.Chat{ID: -100123, Type: botapi.ChatSupergroup, IsForum: true}
+	b.handleStart(botapi.Message{Chat: chat, MessageThreadID: 5, IsTopicMessage: true, Text: "/start"})
+	b.handleStart(botapi.Message{Chat: chat, MessageThreadID: 7, IsTopicMessage: true, Text: "/start"})
+	b.handleStop(botapi.Message{Chat: chat, MessageThreadID: 7, IsTopicMessage: true, Text: "/stop"})
+	assert.Len(t, chats, 1)
+
+	replies := s.Requests("sendMessage")
+	assert.Len(t, replies, 3)
+	assert.Equal(t, "5", replies[0].Param("message_thread_id"))
+	assert.Equal(t, "7", replies[2].Param("message_thread_id"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	webhooks := make(chan alertmanager.Webhook, 1)
+	webhooks <- alertmanager.Webhook{WebhookMessage: notify.WebhookMessage{Data: &template.Data{
+		Status: "firing",
+		Alerts: template.Alerts{{Status: "firing", Labels: template.KV{"alertname": "Fire"}}},
+	}}}
+	go b.sendWebhook(ctx, webhooks)
+	go b.sendNotifications(ctx)
+
+	sent := s.WaitRequests("sendMessage", 4)
+	assert.Len(t, sent, 4)
+	assert.Equal(t, "-100123", sent[3].Param("chat_id"))
+	assert.Equal(t, "5", sent[3].Param("message_thread_id"))
+}
+
+func TestStartKeepsSettings(t *testing.T) {
+	s := botapitest.NewServer()
+	defer s.Close()
+
+	chats := memoryChats{}
+	b := &Bot{logger: log.NewNopLogger(), telegram: s.Client(), chats: chats}
+
+	chat := botapi.Chat{ID: -100123, Type: botapi.ChatSupergroup, Title: "ops"}
+	b.handleStart(botapi.Message{Chat: chat, Text: "/start"})
+
+	subscribed := chats[Recipient{ChatID: chat.ID}]
+	subscribed.Template = "short"
+	subscribed.PinCritical = true
+	subscribed.SilentMatchers = []string{`severity!="critical"`}
+	subscribed.RepeatInterval = 4 * time.Hour
+	chats.Add(subscribed)
+
+	chat.Title = "ops team"
+	b.handleStart(botapi.Message{Chat: chat, Text: "/start severity=critical"})
+
+	restarted := chats[Recipient{ChatID: chat.ID}]
+	assert.Equal(t, "ops team", restarted.Title)
+	assert.Equal(t, "severity=(critical)", restarted.GetFiltersAsString())
+	assert.Equal(t, "short", restarted.Template)
+	assert.True(t, restarted.PinCritical)
+	assert.Equal(t, []string{`severity!="critical"`}, restarted.SilentMatchers)
+	assert.Equal(t, 4*time.Hour, restarted.RepeatInterval)
+}
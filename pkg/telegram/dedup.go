@@ -0,0 +1,105 @@
+package telegram
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/common/model"
+)
+
+// delivery is the state of an alert group last delivered to a chat
+type delivery struct {
+	// alerts are the fingerprints of the group's alerts with their status
+	alerts string
+	at     time.Time
+	// interval is the chat's repeat interval, the delivery is forgotten after it
+	interval time.Duration
+}
+
+// deliveries remembers the last delivered state of every alert group by chat,
+// so repeated notifications of unchanged groups can be suppressed.
+// It's kept in memory only and starts empty after restarts.
+type deliveries struct {
+	mu    sync.Mutex
+	state map[string]delivery
+	// pending are the states of the alert groups queued to be sent to the chats
+	pending map[string]string
+}
+
+// alertsState returns the fingerprints of the alerts with their status, in a stable order.
+// Alerts with changed labels have a different fingerprint.
+func alertsState(alerts template.Alerts) string {
+	states := make([]string, 0, len(alerts))
+	for _, a := range alerts {
+		states = append(states, fmt.Sprintf("%016x:%s", model.LabelsToSignature(a.Labels), a.Status))
+	}
+	sort.Strings(states)
+	return fmt.Sprint(states)
+}
+
+func deliveryKey(r Recipient, groupKey string) string {
+	return chatKey(r) + "/" + groupKey
+}
+
+// unchanged returns true if the alerts of the group were delivered to the recipient
+// in the same state less than interval ago, or are waiting to be sent in the same state
+func (d *deliveries) unchanged(r Recipient, groupKey string, alerts template.Alerts, interval time.Duration, now time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	key, state := deliveryKey(r, groupKey), alertsState(alerts)
+	if pending, ok := d.pending[key]; ok && interval > 0 && pending == state {
+		return true
+	}
+	last, ok := d.state[key]
+	return ok && last.alerts == state && now.Sub(last.at) < interval
+}
+
+// queued remembers the state of the group's alerts queued to be sent to the recipient,
+// until sent is called for them
+func (d *deliveries) queued(r Recipient, groupKey string, alerts template.Alerts) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.pending == nil {
+		d.pending = map[string]string{}
+	}
+	d.pending[deliveryKey(r, groupKey)] = alertsState(alerts)
+}
+
+// sent forgets the state of the group's alerts queued for the recipient, whether sending them succeeded or not
+func (d *deliveries) sent(r Recipient, groupKey string, alerts template.Alerts) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	key := deliveryKey(r, groupKey)
+	if d.pending[key] == alertsState(alerts) {
+		delete(d.pending, key)
+	}
+}
+
+// delivered remembers the state of the group's alerts delivered to the recipient.
+// Resolved groups and groups of chats without interval are forgotten,
+// as are groups last delivered longer ago than their chat's interval.
+func (d *deliveries) delivered(r Recipient, groupKey string, alerts template.Alerts, interval time.Duration, now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.state == nil {
+		d.state = map[string]delivery{}
+	}
+	for key, last := range d.state {
+		if now.Sub(last.at) >= last.interval {
+			delete(d.state, key)
+		}
+	}
+
+	if interval <= 0 || len(alerts.Firing()) == 0 {
+		delete(d.state, deliveryKey(r, groupKey))
+		return
+	}
+	d.state[deliveryKey(r, groupKey)] = delivery{alerts: alertsState(alerts), at: now, interval: interval}
+}
@@ -0,0 +1,135 @@
+package telegram
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/metalmatze/alertmanager-bot/pkg/botapi"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeliveries(t *testing.T) {
+	var d deliveries
+	r := Recipient{ChatID: -100123}
+	now := time.Now()
+
+	disk := template.Alert{Status: "firing", Labels: template.KV{"alertname": "DiskFull", "instance": "db-1"}}
+	other := template.Alert{Status: "firing", Labels: template.KV{"alertname": "DiskFull", "instance": "db-2"}}
+
+	assert.False(t, d.unchanged(r, "group", template.Alerts{disk}, time.Hour, now))
+	d.delivered(r, "group", template.Alerts{disk}, time.Hour, now)
+
+	assert.True(t, d.unchanged(r, "group", template.Alerts{disk}, time.Hour, now.Add(30*time.Minute)))
+	assert.False(t, d.unchanged(r, "group", template.Alerts{disk}, time.Hour, now.Add(time.Hour)), "interval passed")
+	assert.False(t, d.unchanged(r, "other", template.Alerts{disk}, time.Hour, now), "other group")
+	assert.False(t, d.unchanged(Recipient{ChatID: 1}, "group", template.Alerts{disk}, time.Hour, now), "other chat")
+	assert.False(t, d.unchanged(r, "group", template.Alerts{disk, other}, time.Hour, now), "alert added")
+
+	resolved := disk
+	resolved.Status = "resolved"
+	assert.False(t, d.unchanged(r, "group", template.Alerts{resolved}, time.Hour, now), "alert resolved")
+
+	relabeled := disk
+	relabeled.Labels = template.KV{"alertname": "DiskFull", "instance": "db-1", "severity": "critical"}
+	assert.False(t, d.unchanged(r, "group", template.Alerts{relabeled}, time.Hour, now), "labels changed")
+
+	// resolved groups are forgotten
+	d.delivered(r, "group", template.Alerts{resolved}, time.Hour, now)
+	assert.Empty(t, d.state)
+
+	// chats without interval send every notification
+	d.delivered(r, "group", template.Alerts{disk}, 0, now)
+	assert.False(t, d.unchanged(r, "group", template.Alerts{disk}, 0, now))
+	assert.Empty(t, d.state)
+
+	// queued groups aren't queued again until they're sent
+	d.queued(r, "group", template.Alerts{disk})
+	assert.True(t, d.unchanged(r, "group", template.Alerts{disk}, time.Hour, now))
+	assert.False(t, d.unchanged(r, "group", template.Alerts{disk, other}, time.Hour, now), "alert added")
+	assert.False(t, d.unchanged(r, "group", template.Alerts{disk}, 0, now), "no interval")
+	d.sent(r, "group", template.Alerts{disk})
+	assert.False(t, d.unchanged(r, "group", template.Alerts{disk}, time.Hour, now), "sending failed")
+	assert.Empty(t, d.pending)
+}
+
+func TestSendWebhookSuppressesQueued(t *testing.T) {
+	tmpl, err := NewTemplates(&url.URL{}, "../../default.tmpl")
+	assert.NoError(t, err)
+
+	chats := memoryChats{}
+	chats.Add(AugmentedChat{Chat: botapi.Chat{ID: -100123, Type: botapi.ChatSupergroup}, RepeatInterval: time.Hour})
+
+	b := &Bot{
+		logger:    log.NewNopLogger(),
+		chats:     chats,
+		templates: tmpl,
+		metrics:   testMetrics(t),
+		queue:     newNotificationQueue(0, prometheus.NewGauge(prometheus.GaugeOpts{Name: "queued"})),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	webhooks := make(chan alertmanager.Webhook)
+	done := make(chan struct{})
+	go func() {
+		b.sendWebhook(ctx, webhooks)
+		close(done)
+	}()
+
+	w := alertmanager.Webhook{WebhookMessage: notify.WebhookMessage{Data: &template.Data{
+		Status: "firing",
+		Alerts: template.Alerts{{Status: "firing", Labels: template.KV{"alertname": "Fire"}}},
+	}, GroupKey: "{}:{}"}}
+	// Alertmanager sends the same webhook again before the first one was sent
+	webhooks <- w
+	webhooks <- w
+	cancel()
+	<-done
+
+	assert.Equal(t, 1, b.queue.len())
+	assert.Equal(t, float64(1), testutil.ToFloat64(b.metrics.suppressed))
+}
+
+func TestSendWebhookDropsWhenQueueFull(t *testing.T) {
+	tmpl, err := NewTemplates(&url.URL{}, "../../default.tmpl")
+	assert.NoError(t, err)
+
+	chats := memoryChats{}
+	chats.Add(AugmentedChat{Chat: botapi.Chat{ID: -100123, Type: botapi.ChatSupergroup}, RepeatInterval: time.Hour})
+	chats.Add(AugmentedChat{Chat: botapi.Chat{ID: -100456, Type: botapi.ChatSupergroup}, RepeatInterval: time.Hour})
+
+	b := &Bot{
+		logger:    log.NewNopLogger(),
+		chats:     chats,
+		templates: tmpl,
+		metrics:   testMetrics(t),
+		queue:     newNotificationQueue(1, prometheus.NewGauge(prometheus.GaugeOpts{Name: "queued"})),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	webhooks := make(chan alertmanager.Webhook)
+	done := make(chan struct{})
+	go func() {
+		b.sendWebhook(ctx, webhooks)
+		close(done)
+	}()
+
+	webhooks <- alertmanager.Webhook{WebhookMessage: notify.WebhookMessage{Data: &template.Data{
+		Status: "firing",
+		Alerts: template.Alerts{{Status: "firing", Labels: template.KV{"alertname": "Fire"}}},
+	}, GroupKey: "{}:{}"}}
+	cancel()
+	<-done
+
+	assert.Equal(t, 1, b.queue.len())
+	assert.Equal(t, float64(1), testutil.ToFloat64(b.metrics.dropped.WithLabelValues("queue_full")))
+	// the dropped notification isn't suppressed when Alertmanager sends it again
+	assert.Len(t, b.deliveries.pending, 1)
+}
@@ -0,0 +1,247 @@
+package telegram
+
+import (
+	"encoding/json"
+	"fmt"
+	tmplhtml "html/template"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/prometheus/alertmanager/template"
+)
+
+// templateFuncs are available in templates in addition to Alertmanager's default functions
+var templateFuncs = template.FuncMap{
+	// since, until and duration take an optional language, like {{ since .StartsAt $.Language }}
+	"since": func(t time.Time, locale ...string) string {
+		return humanizeDuration(time.Since(t), locale...)
+	},
+	"until": func(t time.Time, locale ...string) string {
+		return humanizeDuration(time.Until(t), locale...)
+	},
+	"duration": func(start time.Time, end time.Time, locale ...string) string {
+		return humanizeDuration(end.Sub(start), locale...)
+	},
+	// tr translates the bot's English words, like {{ tr $.Language "Duration" }}
+	"tr": translate,
+	// sinceDuration is the time passed since t, to be humanized with humanizeDuration
+	"sinceDuration":    time.Since,
+	"silenceURL":       silenceURL,
+	"alertmanagerURL":  alertmanagerURL,
+	"prometheusURL":    prometheusURL,
+	"grafanaURL":       grafanaURL,
+	"alertExpr":        alertExpr,
+	"labelsTable":      labelsTable,
+	"escapeHTML":       escapeHTML,
+	"escapeMarkdownV2": escapeMarkdownV2,
+	"humanizeDuration": humanizeDuration,
+	"severityEmoji":    severityEmoji,
+	"truncate":         truncate,
+}
+
+// labelsFilter returns the matchers selecting exactly the labels, like {alertname="Foo",job="bar"}
+func labelsFilter(labels template.KV) string {
+	matchers := make([]string, 0, len(labels))
+	for _, p := range labels.SortedPairs() {
+		matchers = append(matchers, fmt.Sprintf("%s=%q", p.Name, p.Value))
+	}
+	return "{" + strings.Join(matchers, ",") + "}"
+}
+
+// silenceURL returns the URL of Alertmanager's form to create a silence for the alert
+func silenceURL(externalURL string, a template.Alert) string {
+	return strings.TrimSuffix(externalURL, "/") + "/#/silences/new?filter=" + url.QueryEscape(labelsFilter(a.Labels))
+}
+
+// alertmanagerURL returns the URL of the alert in Alertmanager
+func alertmanagerURL(externalURL string, a template.Alert) string {
+	return strings.TrimSuffix(externalURL, "/") + "/#/alerts?filter=" + url.QueryEscape(labelsFilter(a.Labels))
+}
+
+// prometheusURL returns the URL of the Prometheus that fired the alert, taken from its generator URL
+func prometheusURL(a template.Alert) string {
+	u, err := url.Parse(a.GeneratorURL)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/graph")
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u.String()
+}
+
+// alertExpr returns the expression of the alerting rule, taken from the alert's generator URL
+func alertExpr(a template.Alert) string {
+	u, err := url.Parse(a.GeneratorURL)
+	if err != nil {
+		return ""
+	}
+	return u.Query().Get("g0.expr")
+}
+
+// grafanaURL returns the URL of Grafana's Explore showing the alert's expression
+// queried from the datasource, starting an hour before the alert started.
+func grafanaURL(grafana string, datasource string, a template.Alert) string {
+	expr := alertExpr(a)
+	if expr == "" {
+		return ""
+	}
+
+	from := "now-1h"
+	if !a.StartsAt.IsZero() {
+		from = fmt.Sprintf("%d", a.StartsAt.Add(-time.Hour).UnixNano()/int64(time.Millisecond))
+	}
+
+	left, _ := json.Marshal([]interface{}{from, "now", datasource, map[string]string{"expr": expr}})
+	return strings.TrimSuffix(grafana, "/") + "/explore?left=" + url.QueryEscape(string(left))
+}
+
+// labelsTable renders the labels except the excluded ones as a preformatted table with aligned values
+func labelsTable(labels template.KV, exclude ...string) tmplhtml.HTML {
+	labels = labels.Remove(exclude)
+
+	width := 0
+	for name := range labels {
+		if len(name) > width {
+			width = len(name)
+		}
+	}
+
+	var table strings.Builder
+	table.WriteString("<pre>")
+	for i, p := range labels.SortedPairs() {
+		if i > 0 {
+			table.WriteString("\n")
+		}
+		fmt.Fprintf(&table, "%-*s %s", width, tmplhtml.HTMLEscapeString(p.Name), tmplhtml.HTMLEscapeString(p.Value))
+	}
+	table.WriteString("</pre>")
+
+	return tmplhtml.HTML(table.String())
+}
+
+// markdownV2Replacer escapes all characters that are special in Telegram's MarkdownV2
+var markdownV2Replacer = strings.NewReplacer(
+	`\`, `\\`, "_", `\_`, "*", `\*`, "[", `\[`, "]", `\]`, "(", `\(`, ")", `\)`, "~", `\~`, "`", "\\`",
+	">", `\>`, "#", `\#`, "+", `\+`, "-", `\-`, "=", `\=`, "|", `\|`, "{", `\{`, "}", `\}`, ".", `\.`, "!", `\!`,
+)
+
+// escapeHTML escapes text to be shown as is in a HTML message,
+// it's marked as safe so HTML templates don't escape it again
+func escapeHTML(text string) tmplhtml.HTML {
+	return tmplhtml.HTML(tmplhtml.HTMLEscapeString(text))
+}
+
+// escapeMarkdownV2 escapes text to be shown as is in a MarkdownV2 message
+func escapeMarkdownV2(text string) string {
+	return markdownV2Replacer.Replace(text)
+}
+
+// durationUnit is a unit of durations with its names in a language by count
+type durationUnit struct {
+	d     time.Duration
+	names func(n int64) string
+}
+
+// durationUnits are the units durations are humanized with by language, largest first
+var durationUnits = map[string][]durationUnit{
+	LanguageEnglish: {
+		{24 * time.Hour, simplePlural("day", "days")},
+		{time.Hour, simplePlural("hour", "hours")},
+		{time.Minute, simplePlural("minute", "minutes")},
+		{time.Second, simplePlural("second", "seconds")},
+	},
+	LanguageRussian: {
+		{24 * time.Hour, russianPlural("день", "дня", "дней")},
+		{time.Hour, russianPlural("час", "часа", "часов")},
+		{time.Minute, russianPlural("минута", "минуты", "минут")},
+		{time.Second, russianPlural("секунда", "секунды", "секунд")},
+	},
+}
+
+// simplePlural chooses between the singular and plural form, like in English
+func simplePlural(one, other string) func(n int64) string {
+	return func(n int64) string {
+		if n == 1 {
+			return one
+		}
+		return other
+	}
+}
+
+// russianPlural chooses between the forms for one, few and many, like in Russian
+func russianPlural(one, few, many string) func(n int64) string {
+	return func(n int64) string {
+		switch {
+		case n%10 == 1 && n%100 != 11:
+			return one
+		case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+			return few
+		default:
+			return many
+		}
+	}
+}
+
+// humanizeDuration formats the duration with its two largest units, like "2 hours 5 minutes".
+// The optional locale chooses the language of the units, English is used for unknown ones.
+func humanizeDuration(d time.Duration, locale ...string) string {
+	units := durationUnits[LanguageEnglish]
+	if len(locale) > 0 {
+		if u, ok := durationUnits[languageTag(locale[0])]; ok {
+			units = u
+		}
+	}
+
+	sign := ""
+	if d < 0 {
+		sign = "-"
+		d = -d
+	}
+
+	var parts []string
+	for _, u := range units {
+		if n := int64(d / u.d); n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, u.names(n)))
+			d -= time.Duration(n) * u.d
+		}
+		if len(parts) == 2 {
+			break
+		}
+	}
+	if len(parts) == 0 {
+		last := units[len(units)-1]
+		return fmt.Sprintf("0 %s", last.names(0))
+	}
+
+	return sign + strings.Join(parts, " ")
+}
+
+// severityEmojis are shown for the common severities
+var severityEmojis = map[string]string{
+	"critical": "🔴",
+	"page":     "🔴",
+	"error":    "🟠",
+	"warning":  "🟡",
+	"info":     "🔵",
+	"none":     "⚪",
+}
+
+// severityEmoji returns an emoji for the severity, ❔ for unknown severities
+func severityEmoji(severity string) string {
+	if e, ok := severityEmojis[strings.ToLower(severity)]; ok {
+		return e
+	}
+	return "❔"
+}
+
+// truncate shortens text to at most n characters, ending with … if it was shortened.
+// The arguments are ordered for pipelines like {{ .Annotations.description | truncate 200 }}.
+func truncate(n int, text string) string {
+	r := []rune(text)
+	if n <= 0 || len(r) <= n {
+		return text
+	}
+	return string(r[:n-1]) + "…"
+}
@@ -0,0 +1,80 @@
+package telegram
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/alertmanager/template"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAlertLinks(t *testing.T) {
+	a := template.Alert{
+		Labels:       template.KV{"alertname": "Fire", "job": "x"},
+		StartsAt:     time.Unix(3600, 0),
+		GeneratorURL: "http://prometheus:9090/prom/graph?g0.expr=up+%3D%3D+0&g0.tab=1",
+	}
+
+	assert.Equal(t, "http://alertmanager:9093/#/silences/new?filter=%7Balertname%3D%22Fire%22%2Cjob%3D%22x%22%7D", silenceURL("http://alertmanager:9093/", a))
+	assert.Equal(t, "http://alertmanager:9093/#/alerts?filter=%7Balertname%3D%22Fire%22%2Cjob%3D%22x%22%7D", alertmanagerURL("http://alertmanager:9093", a))
+	assert.Equal(t, "http://prometheus:9090/prom", prometheusURL(a))
+	assert.Equal(t, "up == 0", alertExpr(a))
+	assert.Equal(t,
+		"http://grafana:3000/explore?left=%5B%220%22%2C%22now%22%2C%22Prometheus%22%2C%7B%22expr%22%3A%22up+%3D%3D+0%22%7D%5D",
+		grafanaURL("http://grafana:3000/", "Prometheus", a),
+	)
+
+	assert.Equal(t, "", prometheusURL(template.Alert{}))
+	assert.Equal(t, "", grafanaURL("http://grafana:3000", "Prometheus", template.Alert{}))
+}
+
+func TestLabelsTable(t *testing.T) {
+	labels := template.KV{"alertname": "Fire", "job": "<x>", "instance": "db-1"}
+
+	assert.Equal(t, "<pre>instance db-1\njob      &lt;x&gt;</pre>", string(labelsTable(labels, "alertname")))
+}
+
+func TestEscapeMarkdownV2(t *testing.T) {
+	assert.Equal(t, `disk\_usage \> 90% on db\-1\.example\.com\!`, escapeMarkdownV2("disk_usage > 90% on db-1.example.com!"))
+}
+
+func TestHumanizeDuration(t *testing.T) {
+	tests := []struct {
+		d      time.Duration
+		locale []string
+		out    string
+	}{
+		{d: 0, out: "0 seconds"},
+		{d: time.Second, out: "1 second"},
+		{d: 2*time.Hour + 5*time.Minute + 3*time.Second, out: "2 hours 5 minutes"},
+		{d: 26 * time.Hour, out: "1 day 2 hours"},
+		{d: -90 * time.Second, out: "-1 minute 30 seconds"},
+		{d: 21*time.Hour + 22*time.Minute, locale: []string{"ru"}, out: "21 час 22 минуты"},
+		{d: 11*time.Hour + 5*time.Minute, locale: []string{"ru-RU"}, out: "11 часов 5 минут"},
+		{d: time.Hour, locale: []string{"xx"}, out: "1 hour"},
+		{d: time.Hour, locale: []string{"de_DE"}, out: "1 hour"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.out, humanizeDuration(tt.d, tt.locale...))
+	}
+
+	// every language /lang can choose has units, and only those
+	for _, lang := range languages() {
+		assert.Contains(t, durationUnits, lang)
+	}
+	assert.Len(t, durationUnits, len(languages()))
+}
+
+func TestSeverityEmoji(t *testing.T) {
+	assert.Equal(t, "🔴", severityEmoji("Critical"))
+	assert.Equal(t, "🟡", severityEmoji("warning"))
+	assert.Equal(t, "❔", severityEmoji("custom"))
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "short", truncate(10, "short"))
+	assert.Equal(t, "Disk is f…", truncate(10, "Disk is full on db-1"))
+	assert.Equal(t, "Ünïcödé…", truncate(8, "Ünïcödé text"))
+	assert.Equal(t, "unlimited", truncate(0, "unlimited"))
+}
@@ -0,0 +1,214 @@
+package telegram
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/metalmatze/alertmanager-bot/pkg/botapi"
+	"github.com/prometheus/alertmanager/template"
+)
+
+const (
+	callbackGroups = "groups"
+
+	// maxGroupButtons bounds the number of groups that can be expanded from a /groups message
+	maxGroupButtons = 50
+	// maxGroupButtonText bounds the length of a group's button text
+	maxGroupButtonText = 40
+)
+
+// groupID identifies an alert group by its receiver and labels across requests
+func groupID(g alertmanager.AlertGroup) string {
+	id := g.Receiver.Name
+	for _, p := range template.KV(g.Labels).SortedPairs() {
+		id = id + "\x00" + p.Name + "=" + p.Value
+	}
+	return fmt.Sprintf("%x", sha1.Sum([]byte(id)))[:8]
+}
+
+// groupName returns the values of the group's labels, like "DiskFull / db-1"
+func groupName(g alertmanager.AlertGroup) string {
+	name := strings.Join(template.KV(g.Labels).Values(), " / ")
+	if name == "" {
+		name = g.Receiver.Name
+	}
+	return name
+}
+
+// listGroups returns the alert groups matching the newline separated matchers in a stable order
+func (b *Bot) listGroups(matchers string) ([]alertmanager.AlertGroup, error) {
+	var ms []string
+	if matchers != "" {
+		ms = strings.Split(matchers, "\n")
+	}
+
+	groups, err := alertmanager.ListAlertGroups(b.logger, b.alertmanagerClient, b.alertmanager.String(), ms)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Receiver.Name != groups[j].Receiver.Name {
+			return groups[i].Receiver.Name < groups[j].Receiver.Name
+		}
+		return groupName(groups[i]) < groupName(groups[j])
+	})
+
+	return groups, nil
+}
+
+// groupsOverview renders the alert groups with their alert counts in the language
+// and an inline button for each group to expand its alerts.
+func (b *Bot) groupsOverview(lang string, matchers string) (string, [][]botapi.InlineKeyboardButton, error) {
+	groups, err := b.listGroups(matchers)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var all template.Alerts
+	var list strings.Builder
+	var keyboard [][]botapi.InlineKeyboardButton
+
+	key := b.alertFilters.key(matchers)
+
+	for i, g := range groups {
+		alerts := b.currentTemplates().Data(g.Receiver.Name, nil, g.Alerts...).Alerts
+		all = append(all, alerts...)
+
+		labels := make([]string, 0, len(g.Labels))
+		for _, p := range template.KV(g.Labels).SortedPairs() {
+			labels = append(labels, fmt.Sprintf("%s=%q", p.Name, p.Value))
+		}
+
+		fmt.Fprintf(&list, "%d. <b>%s</b> <code>%s</code>\n%s\n\n",
+			i+1,
+			html.EscapeString(g.Receiver.Name),
+			html.EscapeString(strings.Join(labels, " ")),
+			alertsSummary(lang, alerts),
+		)
+
+		if i < maxGroupButtons {
+			text := fmt.Sprintf("%d. %s", i+1, groupName(g))
+			if r := []rune(text); len(r) > maxGroupButtonText {
+				text = string(r[:maxGroupButtonText-1]) + "…"
+			}
+			text = fmt.Sprintf("%s (%d)", text, len(alerts))
+
+			keyboard = append(keyboard, []botapi.InlineKeyboardButton{{
+				Text:         text,
+				CallbackData: fmt.Sprintf("%s:%s:%s", callbackGroups, groupID(g), key),
+			}})
+		}
+	}
+
+	if len(groups) == 0 {
+		return tr(lang, "No alerts right now! 🎉"), nil, nil
+	}
+
+	header := "<b>" + tr(lang, "%d groups, %s", len(groups), alertsSummary(lang, all)) + "</b>\n"
+	if len(groups) > maxGroupButtons {
+		header = header + "<i>" + tr(lang, "Only the first %d groups can be expanded.", maxGroupButtons) + "</i>\n"
+	}
+
+	text := header + "\n" + list.String()
+	if parts := splitMessage(text, maxMessageLength-partHeaderLength); len(parts) > 1 {
+		text = parts[0] + "\n<i>" + tr(lang, "[list truncated]") + "</i>"
+	}
+
+	return text, keyboard, nil
+}
+
+// groupAlerts renders the alerts of the group with the given ID in the language and a button back to the overview
+func (b *Bot) groupAlerts(recipient Recipient, lang string, matchers string, id string) (string, [][]botapi.InlineKeyboardButton, error) {
+	groups, err := b.listGroups(matchers)
+	if err != nil {
+		return "", nil, err
+	}
+
+	back := [][]botapi.InlineKeyboardButton{{{
+		Text:         tr(lang, "« Back"),
+		CallbackData: fmt.Sprintf("%s::%s", callbackGroups, b.alertFilters.key(matchers)),
+	}}}
+
+	for _, g := range groups {
+		if groupID(g) != id {
+			continue
+		}
+
+		out, err := b.tmplAlerts(recipient, lang, g.Alerts...)
+		if err != nil {
+			return "", nil, err
+		}
+
+		header := fmt.Sprintf("<b>%s</b> – %s\n<b>%s</b>\n",
+			html.EscapeString(groupName(g)),
+			html.EscapeString(g.Receiver.Name),
+			alertsSummary(lang, b.currentTemplates().Data(g.Receiver.Name, nil, g.Alerts...).Alerts),
+		)
+
+		text := header + out
+		if parts := splitMessage(text, maxMessageLength-partHeaderLength); len(parts) > 1 {
+			text = parts[0] + "\n<i>" + tr(lang, "[group truncated]") + "</i>"
+		}
+
+		return text, back, nil
+	}
+
+	return tr(lang, "This group has no alerts anymore."), back, nil
+}
+
+func (b *Bot) handleGroups(message botapi.Message) {
+	lang := b.language(messageRecipient(message), message.From)
+
+	matchers, err := alertmanager.ParseMatchers(strings.Fields(message.Text)[1:])
+	if err != nil {
+		b.reply(messageRecipient(message), err.Error())
+		return
+	}
+
+	text, keyboard, err := b.groupsOverview(lang, strings.Join(matchers, "\n"))
+	if err != nil {
+		b.reply(messageRecipient(message), tr(lang, "failed to list alert groups... %v", err))
+		return
+	}
+
+	if err := b.sendKeyboard(messageRecipient(message), text, keyboard); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to send message", "err", err)
+	}
+}
+
+// handleGroupsCallback expands a group of a /groups message or goes back to the overview
+func (b *Bot) handleGroupsCallback(callback botapi.CallbackQuery, lang string, args []string) string {
+	if len(args) != 2 {
+		return tr(lang, "Invalid group.")
+	}
+
+	matchers, ok := b.alertFilters.filter(args[1])
+	if !ok {
+		return tr(lang, "This list expired, please run %s again.", commandGroups)
+	}
+
+	var text string
+	var keyboard [][]botapi.InlineKeyboardButton
+	var err error
+	if args[0] == "" {
+		text, keyboard, err = b.groupsOverview(lang, matchers)
+	} else {
+		text, keyboard, err = b.groupAlerts(messageRecipient(*callback.Message), lang, matchers, args[0])
+	}
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to render alert groups", "err", err)
+		return tr(lang, "failed to list alert groups... %v", err)
+	}
+
+	if err := b.editMessage(callback.Message.Chat, callback.Message.ID, text, keyboard); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to edit alert groups message", "err", err)
+	}
+
+	return ""
+}
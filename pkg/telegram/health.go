@@ -0,0 +1,71 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/libkv/store"
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/metalmatze/alertmanager-bot/pkg/botapi"
+)
+
+// telegramCheckTTL is how long the result of calling Telegram is reused by readiness checks,
+// so frequent probes don't send a request to Telegram every time
+const telegramCheckTTL = 30 * time.Second
+
+// telegramCheck caches the result of calling getMe
+type telegramCheck struct {
+	mu      sync.Mutex
+	checked time.Time
+	err     error
+}
+
+// Checks checks what the bot depends on to deliver webhooks and returns the errors by name:
+// store, alertmanager, telegram and queue. Checks that succeeded have a nil error.
+func (b *Bot) Checks(ctx context.Context) map[string]error {
+	checks := map[string]error{}
+
+	_, err := b.chats.List()
+	if err == store.ErrKeyNotFound {
+		// no chats subscribed yet
+		err = nil
+	}
+	checks["store"] = err
+
+	checks["alertmanager"] = alertmanager.Healthy(ctx, b.alertmanagerClient, strings.TrimSuffix(b.alertmanager.String(), "/"))
+
+	checks["telegram"] = b.checkTelegram(ctx)
+
+	checks["queue"] = nil
+	if b.queue.full() {
+		// further notifications would be dropped
+		checks["queue"] = fmt.Errorf("the queue is full, %d notifications are queued", b.queue.len())
+	}
+
+	return checks
+}
+
+// checkTelegram calls getMe, unless it was called less than telegramCheckTTL ago,
+// and returns its error
+func (b *Bot) checkTelegram(ctx context.Context) error {
+	c := &b.telegramCheck
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.checked.IsZero() && time.Since(c.checked) < telegramCheckTTL {
+		return c.err
+	}
+
+	var me botapi.User
+	err := b.telegram.Call(ctx, "getMe", struct{}{}, &me)
+	if ctx.Err() != nil {
+		// the probe gave up, that says nothing about Telegram
+		return err
+	}
+	c.err = err
+	c.checked = time.Now()
+	return err
+}
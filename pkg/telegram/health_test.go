@@ -0,0 +1,58 @@
+package telegram
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/metalmatze/alertmanager-bot/pkg/botapi/botapitest"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecks(t *testing.T) {
+	s := botapitest.NewServer()
+	defer s.Close()
+
+	healthy := true
+	am := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/-/healthy", r.URL.Path)
+		if !healthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer am.Close()
+	amURL, err := url.Parse(am.URL)
+	assert.NoError(t, err)
+
+	b := &Bot{
+		logger:             log.NewNopLogger(),
+		telegram:           s.Client(),
+		chats:              memoryChats{},
+		alertmanager:       amURL,
+		alertmanagerClient: http.DefaultClient,
+		queue:              newNotificationQueue(10, prometheus.NewGauge(prometheus.GaugeOpts{Name: "queued"})),
+	}
+
+	checks := b.Checks(context.Background())
+	assert.Len(t, checks, 4)
+	for name, err := range checks {
+		assert.NoError(t, err, name)
+	}
+	assert.Len(t, s.Requests("getMe"), 1)
+
+	healthy = false
+	for i := 0; i < 10; i++ {
+		b.queue.push(notification{message: Message{Text: "firing"}})
+	}
+	checks = b.Checks(context.Background())
+	assert.EqualError(t, checks["alertmanager"], "status code is 503 not 200")
+	assert.EqualError(t, checks["queue"], "the queue is full, 10 notifications are queued")
+	assert.NoError(t, checks["store"])
+	assert.NoError(t, checks["telegram"])
+	// the result of calling Telegram is reused
+	assert.Len(t, s.Requests("getMe"), 1)
+}
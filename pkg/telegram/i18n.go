@@ -0,0 +1,239 @@
+package telegram
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Languages the bot replies in
+const (
+	LanguageEnglish = "en"
+	LanguageRussian = "ru"
+
+	// DefaultLanguage is used for chats without a language and users with an unsupported one
+	DefaultLanguage = LanguageEnglish
+)
+
+// translations of the bot's English messages by language.
+// Messages are looked up by their English format string, missing ones are sent in English.
+var translations = map[string]map[string]string{
+	LanguageEnglish: {},
+	LanguageRussian: {
+		responseStart:   "Привет, %s! Теперь я буду держать тебя в курсе!\nВключённые фильтры: %s\n" + commandHelp,
+		responseStop:    "Хорошо, %s! Больше не буду тебе писать.\n" + commandHelp,
+		responseFilters: responseFiltersRussian,
+		responseHelp:    responseHelpRussian,
+
+		"Sorry, I don't understand...":                        "Извини, я не понимаю...",
+		"I can't add this chat to the subscribers list.":      "Не получается добавить этот чат в подписчики.",
+		"I can't remove this chat from the subscribers list.": "Не получается удалить этот чат из подписчиков.",
+		"I can't list the subscribed chats.":                  "Не получается получить список подписанных чатов.",
+		"I can't get current filters.":                        "Не получается получить текущие фильтры.",
+		"Currently applied filters:\n":                        "Текущие фильтры:\n",
+		"This chat isn't subscribed, please %s first.":        "Этот чат не подписан, сначала отправь %s.",
+
+		"I can't get this chat's template.":                                                   "Не получается получить шаблон этого чата.",
+		"This chat uses the %s template.\nAvailable templates: %s\n\nChoose one with %s name": "Этот чат использует шаблон %s.\nДоступные шаблоны: %s\n\nВыбрать шаблон: %s название",
+		"There's no template %s.\nAvailable templates: %s":                                    "Шаблона %s нет.\nДоступные шаблоны: %s",
+		"I can't change this chat's template.":                                                "Не получается изменить шаблон этого чата.",
+		"Alerts are now sent with the %s template.":                                           "Теперь алерты отправляются с шаблоном %s.",
+
+		"I can't get this chat's language.":                                                                                              "Не получается получить язык этого чата.",
+		"This chat's language is %s.\nAvailable languages: %s\n\nChoose one with %s language":                                            "Язык этого чата: %s.\nДоступные языки: %s\n\nВыбрать язык: %s язык",
+		"There's no language %s.\nAvailable languages: %s":                                                                               "Языка %s нет.\nДоступные языки: %s",
+		"I can't change this chat's language.":                                                                                           "Не получается изменить язык этого чата.",
+		"I can't get this chat's silent alerts.":                                                                                         "Не получается получить тихие алерты этого чата.",
+		"All alerts are sent with sound.\n\nSend some silently with %s matchers, like %s severity!=critical":                             "Все алерты отправляются со звуком.\n\nОтправлять некоторые без звука: %s условия, например %s severity!=critical",
+		"Alerts matching %s are sent silently.\n\nChange them with %s matchers or send all with sound with %s off":                       "Алерты, подходящие под %s, отправляются без звука.\n\nИзменить: %s условия, отправлять все со звуком: %s off",
+		"All alerts are sent with sound now.":                                                                                            "Теперь все алерты отправляются со звуком.",
+		"Alerts matching %s are sent silently now.":                                                                                      "Теперь алерты, подходящие под %s, отправляются без звука.",
+		"I can't change this chat's silent alerts.":                                                                                      "Не получается изменить тихие алерты этого чата.",
+		"Pinning alerts is not enabled.":                                                                                                 "Закрепление алертов не включено.",
+		"I can't get if this chat pins critical alerts.":                                                                                 "Не получается узнать, закрепляет ли этот чат критические алерты.",
+		"Critical alerts are pinned until they're resolved.\n\nStop pinning them with %s off":                                            "Критические алерты закрепляются, пока не решены.\n\nПерестать закреплять: %s off",
+		"Critical alerts aren't pinned.\n\nPin them until they're resolved with %s on":                                                   "Критические алерты не закрепляются.\n\nЗакреплять их, пока не решены: %s on",
+		"Please choose %s on or %s off.":                                                                                                 "Выбери %s on или %s off.",
+		"I can't change if this chat pins critical alerts.":                                                                              "Не получается изменить закрепление критических алертов в этом чате.",
+		"Critical alerts are pinned now, I need to be an admin allowed to pin messages.":                                                 "Теперь критические алерты закрепляются, для этого мне нужны права администратора на закрепление сообщений.",
+		"Critical alerts aren't pinned anymore.":                                                                                         "Критические алерты больше не закрепляются.",
+		"I can't get this chat's repeat interval.":                                                                                       "Не получается получить интервал повтора этого чата.",
+		"Every notification of Alertmanager is sent.\n\nOnly send unchanged alerts again after an interval with %s interval, like %s 4h": "Отправляется каждое уведомление Alertmanager.\n\nПовторять неизменившиеся алерты только через интервал: %s интервал, например %s 4h",
+		"Unchanged alerts are sent again after %s.\n\nChange it with %s interval or send every notification with %s off":                 "Неизменившиеся алерты отправляются снова через %s.\n\nИзменить: %s интервал, отправлять каждое уведомление: %s off",
+		"Every notification of Alertmanager is sent now.":                                                                                "Теперь отправляется каждое уведомление Alertmanager.",
+		"%q is not a valid interval, like 30m, 4h or 1d.":                                                                                "%q – неправильный интервал, например 30m, 4h или 1d.",
+		"Unchanged alerts are sent again after %s now.":                                                                                  "Теперь неизменившиеся алерты отправляются снова через %s.",
+		"I can't change this chat's repeat interval.":                                                                                    "Не получается изменить интервал повтора этого чата.",
+		// every language names itself
+		"I'll reply in English now.": "Теперь я отвечаю по-русски.",
+
+		"failed to render sample alerts with %s template... %v":  "не получилось отрисовать примеры алертов шаблоном %s... %v",
+		"Sample alerts rendered with %s template:":               "Примеры алертов, отрисованные шаблоном %s:",
+		"failed to send sample alerts... %v":                     "не получилось отправить примеры алертов... %v",
+		"No alerts right now to render! 🎉":                       "Сейчас нет алертов для отрисовки! 🎉",
+		"failed to render current alerts with %s template... %v": "не получилось отрисовать текущие алерты шаблоном %s... %v",
+		"Current alerts rendered with %s template:":              "Текущие алерты, отрисованные шаблоном %s:",
+		"failed to send current alerts... %v":                    "не получилось отправить текущие алерты... %v",
+
+		"failed to get status... %v":        "не получилось получить статус... %v",
+		"failed to list alerts... %v":       "не получилось получить список алертов... %v",
+		"failed to list alert groups... %v": "не получилось получить список групп алертов... %v",
+		"failed to list silences... %v":     "не получилось получить список заглушек... %v",
+		"failed to template reply... %v":    "не получилось отрисовать ответ... %v",
+
+		"Named routes are not enabled.":    "Именованные маршруты не включены.",
+		"I can't list the webhook routes.": "Не получается получить список маршрутов вебхуков.",
+		"No named routes yet.\n":           "Именованных маршрутов пока нет.\n",
+		"Currently these webhook routes exist:\n\n%s\nEvery subscribed chat can also be addressed with %s%s<chat_id>.": "Сейчас есть эти маршруты вебхуков:\n\n%s\nКаждому подписанному чату также можно отправлять через %s%s<chat_id>.",
+		" (config file)": " (файл конфигурации)",
+		"Chat %d is part of %s%s in the config file and keeps receiving its webhooks.": "Чат %d входит в %s%s в файле конфигурации и продолжает получать его вебхуки.",
+		"Webhooks for %s%s are now sent to chat %d.":                                   "Вебхуки для %s%s теперь отправляются в чат %d.",
+		"Webhooks for %s%s are no longer sent to chat %d.":                             "Вебхуки для %s%s больше не отправляются в чат %d.",
+		"Please provide a route name and optionally a chat id.":                        "Укажи название маршрута и, если нужно, id чата.",
+		"%q is not a valid chat id.":                                                   "%q – неправильный id чата.",
+		"I can't read the webhook route.":                                              "Не получается прочитать маршрут вебхуков.",
+		"I can't update the webhook route.":                                            "Не получается обновить маршрут вебхуков.",
+
+		"I removed chat %s from the subscribed chats, I can't send to it anymore: %s": "Я удалил чат %s из подписанных, я больше не могу в него писать: %s",
+		"Chat %s was upgraded to a supergroup, its subscription moved to chat %d.":    "Чат %s стал супергруппой, его подписка перенесена в чат %d.",
+		"%s (%d, topic %d)": "%s (%d, тема %d)",
+
+		"see attached": "смотри вложение",
+		"This message is too long for %d messages, it's attached as a file.": "Это сообщение не помещается в %d сообщений, оно приложено файлом.",
+
+		"No alerts right now! 🎉":                          "Сейчас алертов нет! 🎉",
+		"No alerts matching <code>%s</code> right now! 🎉": "Сейчас нет алертов, подходящих под <code>%s</code>! 🎉",
+		"Page %d/%d":     "Страница %d/%d",
+		"« Prev":         "« Назад",
+		"Next »":         "Вперёд »",
+		"« Back":         "« Назад",
+		"Invalid page.":  "Неправильная страница.",
+		"Invalid group.": "Неправильная группа.",
+		"This list expired, please run %s again.":   "Этот список устарел, отправь %s ещё раз.",
+		"[page truncated]":                          "[страница обрезана]",
+		"[list truncated]":                          "[список обрезан]",
+		"[group truncated]":                         "[группа обрезана]",
+		"%d groups, %s":                             "Групп: %d, %s",
+		"Only the first %d groups can be expanded.": "Раскрыть можно только первые %d групп.",
+		"This group has no alerts anymore.":         "В этой группе больше нет алертов.",
+
+		// used by templates
+		"FIRING":                                 "ГОРИТ",
+		"RESOLVED":                               "РЕШЁН",
+		"Duration":                               "Длительность",
+		"Ended":                                  "Завершено",
+		"Started":                                "Начато",
+		"Ends":                                   "Завершится",
+		"Firing for":                             "Горит уже",
+		"Lasted":                                 "Длилось",
+		"Silence":                                "Заглушить",
+		"template":                               "шаблон",
+		"topic":                                  "тема",
+		"ago":                                    "назад",
+		"in":                                     "через",
+		"No silences right now.":                 "Сейчас заглушек нет.",
+		"Currently these chats have subscribed:": "Сейчас подписаны эти чаты:",
+		"No chats have subscribed.":              "Подписанных чатов нет.",
+		"Version":                                "Версия",
+		"Uptime":                                 "Время работы",
+	},
+}
+
+// alertCounts are the forms of "%d alerts" by language
+var alertCounts = map[string]func(n int64) string{
+	LanguageEnglish: simplePlural("%d alert", "%d alerts"),
+	LanguageRussian: russianPlural("%d алерт", "%d алерта", "%d алертов"),
+}
+
+// languages returns the supported languages, sorted
+func languages() []string {
+	langs := make([]string, 0, len(translations))
+	for lang := range translations {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// languageTag returns the language of an IETF language tag or locale, like ru for ru-RU or de_DE
+func languageTag(tag string) string {
+	return strings.ToLower(strings.SplitN(strings.Replace(tag, "_", "-", -1), "-", 2)[0])
+}
+
+// language returns the supported language of an IETF language tag like ru-RU, empty if it isn't supported
+func language(tag string) string {
+	lang := languageTag(tag)
+	if _, ok := translations[lang]; ok {
+		return lang
+	}
+	return ""
+}
+
+// translate returns the translation of the English text
+func translate(lang string, text string) string {
+	if t, ok := translations[lang][text]; ok {
+		return t
+	}
+	return text
+}
+
+// tr translates the English format string and formats it with the arguments
+func tr(lang string, format string, args ...interface{}) string {
+	if len(args) == 0 {
+		return translate(lang, format)
+	}
+	return fmt.Sprintf(translate(lang, format), args...)
+}
+
+// alertsCount returns the number of alerts, like "5 alerts"
+func alertsCount(lang string, n int) string {
+	count, ok := alertCounts[lang]
+	if !ok {
+		count = alertCounts[DefaultLanguage]
+	}
+	return fmt.Sprintf(count(int64(n)), n)
+}
+
+const responseFiltersRussian = `
+Фильтры задаются аргументами команды ` + commandStart + `.
+
+По умолчанию: без аргументов, отправляются все алерты.
+Все метки, не переданные аргументами, разрешены.
+Можно передать несколько меток.
+Аргументы разделяются пробелами.
+Каждый аргумент разрешает алерты с меткой с одним из значений.
+
+Примеры:
+` + commandStart + ` x=test - разрешить метку 'x' только со значением 'test'
+` + commandStart + ` a=x=y=z - разрешить метку 'a' с любым значением из 'x,y,z'
+` + commandStart + ` key=_ - разрешить алерты без метки 'key'
+` + commandStart + ` key=* - разрешить метку 'key' с любым значением
+` + commandStart + ` key=!x - запретить всё (используй ! только с другими операторами)
+` + commandStart + ` key=!x=* - разрешить метку 'key' с любым значением кроме 'x'
+` + commandStart + ` key=!x=*=_ - разрешить ВСЁ кроме метки 'key' со значением 'x'
+` + commandStart + ` key=a env=b - разрешить обе метки 'key' и 'env' с соответствующими значениями
+`
+
+const responseHelpRussian = `
+Я бот Prometheus AlertManager для Telegram. Я буду сообщать тебе об алертах.
+Ещё можно спросить меня про ` + commandStatus + `, ` + commandAlerts + ` и ` + commandSilences + `
+
+Доступные команды:
+` + commandStart + ` [метка=значения ...] - Подписаться на алерты и задать фильтры.
+` + commandStop + ` - Отписаться от алертов.
+` + commandStatus + ` - Показать текущий статус.
+` + commandAlerts + ` [условия ...] - Показать все алерты или подходящие под метка=значение, метка!=значение, метка=~regex или метка!~regex.
+` + commandGroups + ` [условия ...] - Показать алерты, сгруппированные по получателю и меткам группировки.
+` + commandSilences + ` - Показать все заглушки.
+` + commandChats + ` - Показать всех пользователей и группы, которые подписаны.
+` + commandFilters + ` - Подробнее про фильтры.
+` + commandTemplate + ` [название] - Показать или выбрать шаблон, с которым отправляются алерты.
+` + commandTemplateTest + ` [название] - Отрисовать примеры и текущие алерты шаблоном этого чата или указанным.
+` + commandLang + ` [язык] - Показать или выбрать язык, на котором я отвечаю.
+` + commandSilent + ` [условия ...|off] - Показать или выбрать, какие алерты отправляются без звука.
+` + commandPin + ` [on|off] - Показать или выбрать, закрепляются ли критические алерты, пока не решены.
+` + commandRepeat + ` [интервал|off] - Показать или выбрать, как долго неизменившиеся алерты не отправляются снова.
+` + commandRoutes + ` - Показать все маршруты вебхуков.
+` + commandRouteAdd + ` название [chat_id] - Отправлять вебхуки для /webhook/название в этот или указанный чат.
+` + commandRouteDel + ` название [chat_id] - Перестать отправлять вебхуки для /webhook/название в этот или указанный чат.
+`
@@ -0,0 +1,31 @@
+package telegram
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLanguage(t *testing.T) {
+	assert.Equal(t, LanguageRussian, language("ru"))
+	assert.Equal(t, LanguageRussian, language("ru-RU"))
+	assert.Equal(t, LanguageEnglish, language("en_GB"))
+	assert.Equal(t, "", language("xx"))
+	assert.Equal(t, "", language(""))
+}
+
+func TestTr(t *testing.T) {
+	assert.Equal(t, "Страница 2/3", tr(LanguageRussian, "Page %d/%d", 2, 3))
+	assert.Equal(t, "Page 2/3", tr(LanguageEnglish, "Page %d/%d", 2, 3))
+	// messages without translation are sent in English
+	assert.Equal(t, "Untranslated 1", tr(LanguageRussian, "Untranslated %d", 1))
+	assert.Equal(t, "Page 2/3", tr("xx", "Page %d/%d", 2, 3))
+}
+
+func TestAlertsCount(t *testing.T) {
+	assert.Equal(t, "1 alert", alertsCount(LanguageEnglish, 1))
+	assert.Equal(t, "21 алерт", alertsCount(LanguageRussian, 21))
+	assert.Equal(t, "3 алерта", alertsCount(LanguageRussian, 3))
+	assert.Equal(t, "12 алертов", alertsCount(LanguageRussian, 12))
+	assert.Equal(t, "5 alerts", alertsCount("xx", 5))
+}
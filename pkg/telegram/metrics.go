@@ -0,0 +1,95 @@
+package telegram
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// metrics are the bot's Prometheus metrics
+type metrics struct {
+	commands *prometheus.CounterVec
+	// notifications are the notifications sent by chat type and result
+	notifications *prometheus.CounterVec
+	sendDuration  *prometheus.HistogramVec
+	queueDuration prometheus.Histogram
+	queued        prometheus.Gauge
+	webhookAlerts prometheus.Histogram
+	filtered      prometheus.Counter
+	suppressed    prometheus.Counter
+	coalesced     prometheus.Counter
+	dropped       *prometheus.CounterVec
+	throttled     *prometheus.CounterVec
+}
+
+// newMetrics returns the bot's metrics registered with r
+func newMetrics(r prometheus.Registerer) (*metrics, error) {
+	m := &metrics{
+		commands: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "alertmanagerbot",
+			Name:      "commands_total",
+			Help:      "Number of commands received by command name",
+		}, []string{"command"}),
+		notifications: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "alertmanagerbot",
+			Name:      "notifications_total",
+			Help:      "Number of notifications sent to chats by chat type and result, success or failure",
+		}, []string{"chat_type", "result"}),
+		sendDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "alertmanagerbot",
+			Name:      "notification_send_duration_seconds",
+			Help:      "Time it took to send a notification to a chat by chat type, including waiting for rate limits",
+			Buckets:   []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+		}, []string{"chat_type"}),
+		queueDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: "alertmanagerbot",
+			Name:      "notification_queue_duration_seconds",
+			Help:      "Time notifications waited in the queue until a worker started sending them",
+			Buckets:   []float64{.01, .1, .5, 1, 5, 10, 30, 60, 300},
+		}),
+		queued: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "alertmanagerbot",
+			Name:      "notifications_queued",
+			Help:      "Number of notifications waiting to be sent to a chat",
+		}),
+		webhookAlerts: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: "alertmanagerbot",
+			Name:      "webhook_alerts",
+			Help:      "Number of alerts in the webhooks received from Alertmanager",
+			Buckets:   []float64{1, 2, 5, 10, 20, 50, 100, 200, 500},
+		}),
+		filtered: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "alertmanagerbot",
+			Name:      "notifications_filtered_total",
+			Help:      "Number of notifications that weren't sent to a chat because of its filters",
+		}),
+		suppressed: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "alertmanagerbot",
+			Name:      "notifications_suppressed_total",
+			Help:      "Number of repeated notifications of unchanged alert groups that weren't sent to a chat",
+		}),
+		coalesced: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "alertmanagerbot",
+			Name:      "notifications_coalesced_total",
+			Help:      "Number of notifications queued for a chat that were sent together with the one before",
+		}),
+		dropped: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "alertmanagerbot",
+			Name:      "notifications_dropped_total",
+			Help:      "Number of notifications that weren't sent to a chat by reason, the queue being full or the bot stopping",
+		}, []string{"reason"}),
+		throttled: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "alertmanagerbot",
+			Name:      "telegram_throttled_total",
+			Help:      "Number of requests to Telegram held back by reason, a chat's or the global rate limit or Telegram's retry_after",
+		}, []string{"reason"}),
+	}
+
+	for _, c := range []prometheus.Collector{
+		m.commands, m.notifications, m.sendDuration, m.queueDuration, m.queued,
+		m.webhookAlerts, m.filtered, m.suppressed, m.coalesced, m.dropped, m.throttled,
+	} {
+		if err := r.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
@@ -0,0 +1,38 @@
+package telegram
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+// testMetrics returns metrics registered with a registry of their own
+func testMetrics(t *testing.T) *metrics {
+	m, err := newMetrics(prometheus.NewRegistry())
+	assert.NoError(t, err)
+	return m
+}
+
+func TestNewMetrics(t *testing.T) {
+	r := prometheus.NewRegistry()
+	m, err := newMetrics(r)
+	assert.NoError(t, err)
+
+	m.notifications.WithLabelValues("private", "success").Inc()
+	m.sendDuration.WithLabelValues("private").Observe(1)
+
+	families, err := r.Gather()
+	assert.NoError(t, err)
+	names := map[string]bool{}
+	for _, f := range families {
+		names[f.GetName()] = true
+	}
+	assert.True(t, names["alertmanagerbot_notifications_total"])
+	assert.True(t, names["alertmanagerbot_notification_send_duration_seconds"])
+	assert.True(t, names["alertmanagerbot_webhook_alerts"])
+
+	// a second bot needs a registry of its own
+	_, err = newMetrics(r)
+	assert.Error(t, err)
+}
@@ -0,0 +1,162 @@
+package telegram
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"html"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/metalmatze/alertmanager-bot/pkg/botapi"
+	"github.com/prometheus/alertmanager/types"
+)
+
+const (
+	callbackAlerts = "alerts"
+
+	// maxAlertFilters bounds the number of remembered filters of paginated /alerts messages
+	maxAlertFilters = 1000
+)
+
+// alertFilters remembers the filters of paginated /alerts messages by a short key,
+// as the callback data of inline buttons is limited to 64 bytes.
+type alertFilters struct {
+	mu      sync.Mutex
+	filters map[string]string
+}
+
+// key returns the key for a filter and remembers the filter by it
+func (f *alertFilters) key(filter string) string {
+	if filter == "" {
+		return ""
+	}
+
+	key := fmt.Sprintf("%x", sha1.Sum([]byte(filter)))[:10]
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.filters == nil || len(f.filters) >= maxAlertFilters {
+		f.filters = map[string]string{}
+	}
+	f.filters[key] = filter
+
+	return key
+}
+
+// filter returns the filter remembered by key
+func (f *alertFilters) filter(key string) (string, bool) {
+	if key == "" {
+		return "", true
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	filter, ok := f.filters[key]
+	return filter, ok
+}
+
+// alertsPage renders a page of the alerts matching filter in the language with a header counting them by severity
+// and the inline keyboard to navigate to the previous and next page.
+func (b *Bot) alertsPage(recipient Recipient, lang string, filter string, alerts []*types.Alert, page int) (string, [][]botapi.InlineKeyboardButton, error) {
+	if len(alerts) == 0 {
+		if filter != "" {
+			return tr(lang, "No alerts matching <code>%s</code> right now! 🎉", html.EscapeString(filter)), nil, nil
+		}
+		return tr(lang, "No alerts right now! 🎉"), nil, nil
+	}
+
+	// Alertmanager doesn't guarantee any order, pages need a stable one
+	sort.Slice(alerts, func(i, j int) bool {
+		if !alerts[i].StartsAt.Equal(alerts[j].StartsAt) {
+			return alerts[i].StartsAt.After(alerts[j].StartsAt)
+		}
+		return alerts[i].Fingerprint() < alerts[j].Fingerprint()
+	})
+
+	pages := (len(alerts) + b.pageSize - 1) / b.pageSize
+	if page >= pages {
+		page = pages - 1
+	}
+	if page < 0 {
+		page = 0
+	}
+
+	end := (page + 1) * b.pageSize
+	if end > len(alerts) {
+		end = len(alerts)
+	}
+
+	out, err := b.tmplAlerts(recipient, lang, alerts[page*b.pageSize:end]...)
+	if err != nil {
+		return "", nil, err
+	}
+
+	header := "<b>" + alertsSummary(lang, b.currentTemplates().Data("default", nil, alerts...).Alerts) + "</b>"
+	if filter != "" {
+		header = header + fmt.Sprintf("\n<code>%s</code>", html.EscapeString(filter))
+	}
+	if pages > 1 {
+		header = header + "\n" + tr(lang, "Page %d/%d", page+1, pages)
+	}
+
+	text := header + "\n" + out
+	if parts := splitMessage(text, maxMessageLength-partHeaderLength); len(parts) > 1 {
+		text = parts[0] + "\n<i>" + tr(lang, "[page truncated]") + "</i>"
+	}
+
+	key := b.alertFilters.key(filter)
+	button := func(text string, page int) botapi.InlineKeyboardButton {
+		return botapi.InlineKeyboardButton{Text: text, CallbackData: fmt.Sprintf("%s:%d:%s", callbackAlerts, page, key)}
+	}
+
+	var row []botapi.InlineKeyboardButton
+	if page > 0 {
+		row = append(row, button(tr(lang, "« Prev"), page-1))
+	}
+	row = append(row, button("↻", page))
+	if page < pages-1 {
+		row = append(row, button(tr(lang, "Next »"), page+1))
+	}
+
+	return text, [][]botapi.InlineKeyboardButton{row}, nil
+}
+
+// handleAlertsCallback shows the page of alerts requested by an inline button of an /alerts message
+func (b *Bot) handleAlertsCallback(callback botapi.CallbackQuery, lang string, args []string) string {
+	if len(args) != 2 {
+		return tr(lang, "Invalid page.")
+	}
+
+	page, err := strconv.Atoi(args[0])
+	if err != nil {
+		return tr(lang, "Invalid page.")
+	}
+
+	filter, ok := b.alertFilters.filter(args[1])
+	if !ok {
+		return tr(lang, "This list expired, please run %s again.", commandAlerts)
+	}
+
+	alerts, err := alertmanager.ListAlerts(b.logger, b.alertmanagerClient, b.alertmanager.String(), filter)
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to list alerts", "err", err)
+		return tr(lang, "failed to list alerts... %v", err)
+	}
+
+	text, keyboard, err := b.alertsPage(messageRecipient(*callback.Message), lang, filter, alerts, page)
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to render alerts page", "err", err)
+		return tr(lang, "failed to list alerts... %v", err)
+	}
+
+	if err := b.editMessage(callback.Message.Chat, callback.Message.ID, text, keyboard); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to edit alerts message", "err", err)
+	}
+
+	return ""
+}
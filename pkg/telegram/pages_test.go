@@ -0,0 +1,27 @@
+package telegram
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAlertFilters(t *testing.T) {
+	var f alertFilters
+
+	assert.Equal(t, "", f.key(""))
+	filter, ok := f.filter("")
+	assert.True(t, ok)
+	assert.Equal(t, "", filter)
+
+	key := f.key(`{severity="critical"}`)
+	assert.Len(t, key, 10)
+	assert.Equal(t, key, f.key(`{severity="critical"}`))
+
+	filter, ok = f.filter(key)
+	assert.True(t, ok)
+	assert.Equal(t, `{severity="critical"}`, filter)
+
+	_, ok = f.filter("unknown")
+	assert.False(t, ok)
+}
@@ -0,0 +1,94 @@
+package telegram
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/botapi"
+)
+
+// Parse modes Telegram formats messages with
+const (
+	ParseModeHTML       = "HTML"
+	ParseModeMarkdownV2 = "MarkdownV2"
+	ParseModePlain      = "plain"
+)
+
+// partHeaders are prepended to every part of a split message by parse mode
+var partHeaders = map[string]string{
+	ParseModeHTML:       partHeader,
+	ParseModeMarkdownV2: "_\\[%d/%d\\]_\n",
+	ParseModePlain:      "[%d/%d]\n",
+}
+
+// validParseMode returns true for the parse modes templates can choose
+func validParseMode(mode string) bool {
+	_, ok := partHeaders[mode]
+	return ok
+}
+
+// apiParseMode returns the Bot API's parse mode for the parse mode
+func apiParseMode(mode string) string {
+	switch mode {
+	case ParseModeHTML:
+		return botapi.ModeHTML
+	case ParseModeMarkdownV2:
+		return botapi.ModeMarkdownV2
+	default:
+		return botapi.ModeDefault
+	}
+}
+
+// toPlainText removes the formatting of a message in the parse mode
+func toPlainText(text string, mode string) string {
+	switch mode {
+	case ParseModeHTML:
+		return plainText(text)
+	case ParseModeMarkdownV2:
+		return markdownV2PlainText(text)
+	default:
+		return text
+	}
+}
+
+// splitText splits a message in the parse mode into parts that are each at most limit long.
+// HTML messages keep their formatting intact across parts, MarkdownV2 messages are split outside of entities
+// and plain messages are split like HTML escaped text.
+func splitText(text string, mode string, limit int) []string {
+	switch mode {
+	case ParseModeHTML:
+		return splitMessage(text, limit)
+	case ParseModeMarkdownV2:
+		return splitMarkdownV2(text, limit)
+	}
+
+	parts := splitMessage(html.EscapeString(text), limit)
+	for i, part := range parts {
+		parts[i] = html.UnescapeString(part)
+	}
+	return parts
+}
+
+// numberParts prepends a header like [2/3] to every part if there's more than one
+func numberParts(parts []string, mode string) []string {
+	if len(parts) < 2 {
+		return parts
+	}
+
+	header, ok := partHeaders[mode]
+	if !ok {
+		header = partHeaders[ParseModePlain]
+	}
+
+	numbered := make([]string, len(parts))
+	for i, part := range parts {
+		numbered[i] = fmt.Sprintf(header, i+1, len(parts)) + part
+	}
+	return numbered
+}
+
+// isEntitiesError returns true if Telegram rejected a message because its formatting is invalid
+func isEntitiesError(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "can't parse entities")
+}
@@ -0,0 +1,88 @@
+package telegram
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/metalmatze/alertmanager-bot/pkg/botapi"
+	"github.com/metalmatze/alertmanager-bot/pkg/botapi/botapitest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNumberParts(t *testing.T) {
+	assert.Equal(t, []string{"a"}, numberParts([]string{"a"}, ParseModeHTML))
+	assert.Equal(t, []string{"<i>[1/2]</i>\na", "<i>[2/2]</i>\nb"}, numberParts([]string{"a", "b"}, ParseModeHTML))
+	assert.Equal(t, []string{"_\\[1/2\\]_\na", "_\\[2/2\\]_\nb"}, numberParts([]string{"a", "b"}, ParseModeMarkdownV2))
+	assert.Equal(t, []string{"[1/2]\na", "[2/2]\nb"}, numberParts([]string{"a", "b"}, ParseModePlain))
+}
+
+func TestSplitText(t *testing.T) {
+	assert.Equal(t, []string{"a < b", "c & d"}, splitText("a < b\n\nc & d", ParseModePlain, 6))
+	assert.Equal(t, []string{"<b>a</b>", "<b>b</b>"}, splitText("<b>a\n\nb</b>", ParseModeHTML, 2))
+	assert.Equal(t, []string{`*a\.*`, `*b\.*`}, splitText("*a\\.*\n\n*b\\.*", ParseModeMarkdownV2, 3))
+}
+
+func TestToPlainText(t *testing.T) {
+	assert.Equal(t, "a < b", toPlainText("<b>a</b> &lt; b", ParseModeHTML))
+	assert.Equal(t, `see Grafana (http://grafana/?a=1&b=2)`, toPlainText(`see <a href="http://grafana/?a=1&amp;b=2">Grafana</a>`, ParseModeHTML))
+	assert.Equal(t, "db-1.example.com", toPlainText(`*db\-1\.example\.com*`, ParseModeMarkdownV2))
+	assert.Equal(t, "bold italic under strike spoiler code 1*2",
+		toPlainText("*bold* _italic_ __under__ ~strike~ ||spoiler|| `code` 1\\*2", ParseModeMarkdownV2))
+	assert.Equal(t, "see Grafana (http://grafana/(x))", toPlainText(`see [Grafana](http://grafana/(x\))`, ParseModeMarkdownV2))
+	assert.Equal(t, "<b>a</b>", toPlainText("<b>a</b>", ParseModePlain))
+}
+
+func TestSendMessagePlainTextFallback(t *testing.T) {
+	s := botapitest.NewServer()
+	defer s.Close()
+	s.Handle("sendMessage", func(r botapitest.Request) (interface{}, *botapi.Error) {
+		if r.Param("parse_mode") != "" {
+			return nil, &botapi.Error{Code: 400, Description: "Bad Request: can't parse entities: unclosed tag"}
+		}
+		return botapi.Message{ID: 1}, nil
+	})
+
+	b := &Bot{logger: log.NewNopLogger(), telegram: s.Client()}
+	id, err := b.sendMessage(Recipient{ChatID: -100}, DefaultLanguage, Message{Text: "<b>a & b", ParseMode: ParseModeHTML, Silent: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, id)
+
+	requests := s.Requests("sendMessage")
+	assert.Len(t, requests, 2)
+	assert.Equal(t, "-100", requests[1].Param("chat_id"))
+	assert.Equal(t, "a & b", requests[1].Param("text"))
+	assert.Equal(t, "true", requests[1].Param("disable_notification"))
+}
+
+func TestSendMessagePlainTextFallbackSplit(t *testing.T) {
+	s := botapitest.NewServer()
+	defer s.Close()
+	s.Handle("sendMessage", func(r botapitest.Request) (interface{}, *botapi.Error) {
+		if r.Param("parse_mode") != "" {
+			return nil, &botapi.Error{Code: 400, Description: "Bad Request: can't parse entities: unclosed tag"}
+		}
+		return botapi.Message{ID: len(r.Param("text"))}, nil
+	})
+
+	// the URLs of the links make the plain text longer than a single message
+	link := `<a href="http://grafana.example.com/` + strings.Repeat("x", 100) + `">a</a>`
+	b := &Bot{logger: log.NewNopLogger(), telegram: s.Client()}
+	_, err := b.sendMessage(Recipient{ChatID: -100}, DefaultLanguage, Message{
+		Text:      strings.Repeat(link+"\n", 60),
+		ParseMode: ParseModeHTML,
+	})
+	assert.NoError(t, err)
+
+	var plain []botapitest.Request
+	for _, r := range s.Requests("sendMessage") {
+		if r.Param("parse_mode") == "" {
+			plain = append(plain, r)
+		}
+	}
+	assert.Len(t, plain, 2)
+	for _, r := range plain {
+		assert.True(t, utf16Length(r.Param("text")) <= maxMessageLength)
+		assert.NotContains(t, r.Param("text"), "<a")
+	}
+}
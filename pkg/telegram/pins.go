@@ -0,0 +1,220 @@
+package telegram
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+
+	"github.com/docker/libkv/store"
+	"github.com/go-kit/kit/log/level"
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/metalmatze/alertmanager-bot/pkg/botapi"
+	"github.com/prometheus/common/model"
+)
+
+const (
+	telegramPinsDirectory = "telegram/pins"
+
+	// pinSeverity is the severity of alerts whose groups are pinned
+	pinSeverity = "critical"
+)
+
+// Pin is the pinned message of a firing critical alert group in a chat or forum topic
+type Pin struct {
+	GroupKey  string
+	ChatID    int64
+	ThreadID  int `json:",omitempty"`
+	MessageID int
+}
+
+// Recipient returns the chat or forum topic the message is pinned in
+func (p Pin) Recipient() Recipient {
+	return Recipient{ChatID: p.ChatID, ThreadID: p.ThreadID}
+}
+
+// PinStore writes the pinned messages to a libkv store backend
+type PinStore struct {
+	kv store.Store
+}
+
+// NewPinStore stores pinned messages in the provided kv backend
+func NewPinStore(kv store.Store) (*PinStore, error) {
+	return &PinStore{kv: kv}, nil
+}
+
+// List all pinned messages saved in the kv backend
+func (s *PinStore) List() ([]Pin, error) {
+	kvPairs, err := s.kv.List(telegramPinsDirectory)
+	if err == store.ErrKeyNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var pins []Pin
+	for _, kv := range kvPairs {
+		var p Pin
+		if err := json.Unmarshal(kv.Value, &p); err != nil {
+			return nil, err
+		}
+		pins = append(pins, p)
+	}
+
+	return pins, nil
+}
+
+// Get the pinned message of the alert group in the chat or forum topic from the kv backend.
+// If there's no such pin store.ErrKeyNotFound is returned.
+func (s *PinStore) Get(r Recipient, groupKey string) (Pin, error) {
+	var p Pin
+
+	kv, err := s.kv.Get(pinKey(r, groupKey))
+	if err != nil {
+		return p, err
+	}
+
+	err = json.Unmarshal(kv.Value, &p)
+	return p, err
+}
+
+// Add a pinned message to the kv backend, replacing the previous one of its alert group
+func (s *PinStore) Add(p Pin) error {
+	b, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	return s.kv.Put(pinKey(p.Recipient(), p.GroupKey), b, nil)
+}
+
+// Remove a pinned message from the kv backend
+func (s *PinStore) Remove(p Pin) error {
+	return s.kv.Delete(pinKey(p.Recipient(), p.GroupKey))
+}
+
+// pinKey returns the key of the alert group's pin, group keys are hashed as they can contain any character
+func pinKey(r Recipient, groupKey string) string {
+	return fmt.Sprintf("%s/%d:%d/%x", telegramPinsDirectory, r.ChatID, r.ThreadID, sha1.Sum([]byte(groupKey)))
+}
+
+// isCritical returns true if the webhook's group has firing critical alerts
+func isCritical(w alertmanager.Webhook) bool {
+	for _, a := range w.Alerts.Firing() {
+		if a.Labels["severity"] == pinSeverity {
+			return true
+		}
+	}
+	return false
+}
+
+// sendPinned sends the notification of the webhook to a chat that pins critical alert groups.
+// The message of a firing critical group is pinned, replacing the group's previous pin.
+// Once the group is resolved its pinned message is edited to the resolved notification and unpinned.
+func (b *Bot) sendPinned(chat AugmentedChat, lang string, w alertmanager.Webhook, m Message) error {
+	recipient := chat.Recipient()
+
+	pin, err := b.pins.Get(recipient, w.GroupKey)
+	if err != nil && err != store.ErrKeyNotFound {
+		level.Warn(b.logger).Log("msg", "failed to get pinned message", "err", err)
+	}
+	pinned := err == nil
+
+	if !isCritical(w) {
+		if !pinned {
+			_, err := b.sendMessage(recipient, lang, m)
+			return err
+		}
+
+		b.unpin(pin)
+		if w.Status == string(model.AlertResolved) {
+			err := b.editNotification(recipient, pin.MessageID, m)
+			if err == nil {
+				return nil
+			}
+			level.Warn(b.logger).Log("msg", "failed to edit pinned message, sending a new one", "err", err)
+		}
+		_, err := b.sendMessage(recipient, lang, m)
+		return err
+	}
+
+	id, err := b.sendMessage(recipient, lang, m)
+	if err != nil {
+		return err
+	}
+
+	err = b.throttle.do(recipient.ChatID, func() error {
+		return b.telegram.PinChatMessage(recipient.ChatID, id, true)
+	})
+	if err != nil {
+		// the bot needs to be an admin allowed to pin messages
+		level.Warn(b.logger).Log("msg", "failed to pin message", "chat_id", recipient.ChatID, "err", err)
+		return nil
+	}
+	if pinned {
+		b.unpin(pin)
+	}
+
+	pin = Pin{GroupKey: w.GroupKey, ChatID: recipient.ChatID, ThreadID: recipient.ThreadID, MessageID: id}
+	if err := b.pins.Add(pin); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to add pinned message to pin store", "err", err)
+	}
+	return nil
+}
+
+// unpin unpins the message and forgets it, failures are logged
+func (b *Bot) unpin(pin Pin) {
+	err := b.throttle.do(pin.ChatID, func() error {
+		return b.telegram.UnpinChatMessage(pin.ChatID, pin.MessageID)
+	})
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to unpin message", "chat_id", pin.ChatID, "err", err)
+	}
+	if err := b.pins.Remove(pin); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to remove pinned message from pin store", "err", err)
+	}
+}
+
+// unpinAll unpins all messages pinned in the chat or forum topic and forgets them, failures are logged
+func (b *Bot) unpinAll(r Recipient) {
+	if b.pins == nil {
+		return
+	}
+
+	pins, err := b.pins.List()
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to list pinned messages", "chat_id", r.ChatID, "err", err)
+		return
+	}
+	for _, pin := range pins {
+		if pin.Recipient() == r {
+			b.unpin(pin)
+		}
+	}
+}
+
+// editNotification replaces the text of a notification sent before, it fails for messages too long to edit
+func (b *Bot) editNotification(recipient Recipient, messageID int, m Message) error {
+	if len(splitText(m.Text, m.ParseMode, maxMessageLength)) > 1 {
+		return fmt.Errorf("message is too long to edit")
+	}
+
+	params := botapi.EditMessageTextParams{
+		ChatID:    recipient.ChatID,
+		MessageID: messageID,
+		Text:      m.Text,
+		ParseMode: apiParseMode(m.ParseMode),
+	}
+	edit := func() error { return b.telegram.EditMessageText(params) }
+
+	err := b.throttle.do(recipient.ChatID, edit)
+	if isEntitiesError(err) {
+		params.Text = toPlainText(m.Text, m.ParseMode)
+		if len(splitText(params.Text, ParseModePlain, maxMessageLength)) > 1 {
+			return fmt.Errorf("message is too long to edit as plain text")
+		}
+		params.ParseMode = botapi.ModeDefault
+		err = b.throttle.do(recipient.ChatID, edit)
+	}
+	return err
+}
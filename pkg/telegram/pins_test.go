@@ -0,0 +1,135 @@
+package telegram
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/libkv/store"
+	"github.com/docker/libkv/store/boltdb"
+	"github.com/go-kit/kit/log"
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/metalmatze/alertmanager-bot/pkg/botapi"
+	"github.com/metalmatze/alertmanager-bot/pkg/botapi/botapitest"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryPins is a BotPinStore keeping the pins in memory
+type memoryPins map[string]Pin
+
+func (m memoryPins) List() ([]Pin, error) {
+	var pins []Pin
+	for _, p := range m {
+		pins = append(pins, p)
+	}
+	return pins, nil
+}
+
+func (m memoryPins) Get(r Recipient, groupKey string) (Pin, error) {
+	p, ok := m[pinKey(r, groupKey)]
+	if !ok {
+		return p, store.ErrKeyNotFound
+	}
+	return p, nil
+}
+
+func (m memoryPins) Add(p Pin) error {
+	m[pinKey(p.Recipient(), p.GroupKey)] = p
+	return nil
+}
+
+func (m memoryPins) Remove(p Pin) error {
+	delete(m, pinKey(p.Recipient(), p.GroupKey))
+	return nil
+}
+
+func TestSendPinned(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pins")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	kv, err := boltdb.New([]string{filepath.Join(dir, "bot.db")}, &store.Config{Bucket: "alertmanager"})
+	assert.NoError(t, err)
+	defer kv.Close()
+	pins, err := NewPinStore(kv)
+	assert.NoError(t, err)
+
+	s := botapitest.NewServer()
+	defer s.Close()
+
+	b := &Bot{logger: log.NewNopLogger(), telegram: s.Client(), pins: pins}
+	chat := AugmentedChat{Chat: botapi.Chat{ID: -100123, Type: botapi.ChatSupergroup}, PinCritical: true}
+
+	webhook := func(status string, severity string) alertmanager.Webhook {
+		return alertmanager.Webhook{WebhookMessage: notify.WebhookMessage{
+			GroupKey: `{}:{alertname="DiskFull"}`,
+			Data: &template.Data{Status: status, Alerts: template.Alerts{{
+				Status: status,
+				Labels: template.KV{"alertname": "DiskFull", "severity": severity},
+			}}},
+		}}
+	}
+
+	// firing critical groups are pinned, replacing their previous pin
+	assert.NoError(t, b.sendPinned(chat, DefaultLanguage, webhook("firing", "critical"), Message{Text: "firing"}))
+	assert.NoError(t, b.sendPinned(chat, DefaultLanguage, webhook("firing", "critical"), Message{Text: "still firing"}))
+
+	pinned := s.Requests("pinChatMessage")
+	assert.Len(t, pinned, 2)
+	assert.Equal(t, "1", pinned[0].Param("message_id"))
+	assert.Equal(t, "2", pinned[1].Param("message_id"))
+	assert.Equal(t, "1", s.Requests("unpinChatMessage")[0].Param("message_id"))
+
+	pin, err := pins.Get(chat.Recipient(), `{}:{alertname="DiskFull"}`)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, pin.MessageID)
+
+	// resolved groups edit and unpin their pinned message
+	assert.NoError(t, b.sendPinned(chat, DefaultLanguage, webhook("resolved", "critical"), Message{Text: "resolved"}))
+	assert.Len(t, s.Requests("sendMessage"), 2)
+
+	edited := s.Requests("editMessageText")
+	assert.Len(t, edited, 1)
+	assert.Equal(t, "2", edited[0].Param("message_id"))
+	assert.Equal(t, "resolved", edited[0].Param("text"))
+	assert.Equal(t, "2", s.Requests("unpinChatMessage")[1].Param("message_id"))
+
+	_, err = pins.Get(chat.Recipient(), `{}:{alertname="DiskFull"}`)
+	assert.Equal(t, store.ErrKeyNotFound, err)
+
+	// other groups are just sent
+	assert.NoError(t, b.sendPinned(chat, DefaultLanguage, webhook("firing", "warning"), Message{Text: "warning"}))
+	assert.Len(t, s.Requests("sendMessage"), 3)
+	assert.Len(t, s.Requests("pinChatMessage"), 2)
+}
+
+func TestOptOutUnpins(t *testing.T) {
+	s := botapitest.NewServer()
+	defer s.Close()
+
+	chat := botapi.Chat{ID: -100123, Type: botapi.ChatSupergroup}
+	chats := memoryChats{}
+	chats.Add(AugmentedChat{Chat: chat, PinCritical: true})
+	pins := memoryPins{}
+	pins.Add(Pin{GroupKey: "{}:{a}", ChatID: chat.ID, MessageID: 1})
+	pins.Add(Pin{GroupKey: "{}:{b}", ChatID: chat.ID, MessageID: 2})
+	pins.Add(Pin{GroupKey: "{}:{a}", ChatID: -1, MessageID: 3})
+
+	b := &Bot{logger: log.NewNopLogger(), telegram: s.Client(), chats: chats, pins: pins}
+
+	b.handlePin(botapi.Message{Chat: chat, Text: "/pin off"})
+	assert.False(t, chats[Recipient{ChatID: chat.ID}].PinCritical)
+	assert.Len(t, s.Requests("unpinChatMessage"), 2)
+	assert.Len(t, pins, 1)
+
+	pins.Add(Pin{GroupKey: "{}:{a}", ChatID: chat.ID, MessageID: 4})
+	b.handleStop(botapi.Message{Chat: chat, Text: "/stop"})
+	assert.Len(t, chats, 0)
+	unpinned := s.Requests("unpinChatMessage")
+	assert.Len(t, unpinned, 3)
+	assert.Equal(t, "4", unpinned[2].Param("message_id"))
+	assert.Len(t, pins, 1)
+}
@@ -0,0 +1,272 @@
+package telegram
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/metalmatze/alertmanager-bot/pkg/botapi"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// notification is the message of alert groups waiting to be sent to a chat
+type notification struct {
+	chat     AugmentedChat
+	lang     string
+	message  Message
+	webhooks []alertmanager.Webhook
+	// queued is when the notification was queued
+	queued time.Time
+}
+
+// pinned returns true if the notification is sent by sendPinned, those aren't coalesced
+func (n notification) pinned(b *Bot) bool {
+	return b.pins != nil && n.chat.PinCritical
+}
+
+// notificationQueue queues notifications by chat, so every chat's notifications are sent in order
+// and notifications queued while a chat is rate limited can be sent as one message.
+// A chat's notifications are only handed to one worker at a time.
+type notificationQueue struct {
+	mu     sync.Mutex
+	queued map[Recipient][]notification
+	// size is the number of notifications queued at most, zero is unlimited
+	size int
+	n    int
+	// order are the chats with queued notifications no worker is sending to, the one waiting longest first
+	order []Recipient
+	// sending are the chats a worker is sending notifications to
+	sending map[Recipient]bool
+	ready   chan struct{}
+
+	depth prometheus.Gauge
+}
+
+// newNotificationQueue returns a queue of at most size notifications reporting their number to depth
+func newNotificationQueue(size int, depth prometheus.Gauge) *notificationQueue {
+	return &notificationQueue{
+		queued:  map[Recipient][]notification{},
+		size:    size,
+		sending: map[Recipient]bool{},
+		ready:   make(chan struct{}, 1),
+		depth:   depth,
+	}
+}
+
+// push queues the notification after the chat's other notifications,
+// it returns false without queuing it if the queue is full
+func (q *notificationQueue) push(n notification) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.size > 0 && q.n >= q.size {
+		return false
+	}
+
+	r := n.chat.Recipient()
+	if len(q.queued[r]) == 0 && !q.sending[r] {
+		q.order = append(q.order, r)
+		q.signal()
+	}
+	q.queued[r] = append(q.queued[r], n)
+	q.n++
+	q.depth.Inc()
+	return true
+}
+
+// len returns the number of queued notifications
+func (q *notificationQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.n
+}
+
+// full returns true if further notifications aren't queued
+func (q *notificationQueue) full() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.size > 0 && q.n >= q.size
+}
+
+// drain removes all queued notifications no worker is sending and returns them
+func (q *notificationQueue) drain() []notification {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var drained []notification
+	for r, queued := range q.queued {
+		drained = append(drained, queued...)
+		delete(q.queued, r)
+	}
+	q.order = nil
+	q.n = 0
+	q.depth.Set(0)
+	return drained
+}
+
+// signal wakes up a worker waiting for notifications
+func (q *notificationQueue) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// pop returns all queued notifications of the chat waiting longest, it waits for notifications until ctx is done.
+// Once ctx is done it returns false even if notifications are queued.
+// The chat's notifications queued later are only returned again once done was called for it.
+func (q *notificationQueue) pop(ctx context.Context) (Recipient, []notification, bool) {
+	for {
+		if ctx.Err() != nil {
+			return Recipient{}, nil, false
+		}
+
+		q.mu.Lock()
+		if len(q.order) > 0 {
+			r := q.order[0]
+			q.order = q.order[1:]
+			queued := q.queued[r]
+			delete(q.queued, r)
+			q.n -= len(queued)
+			q.sending[r] = true
+			q.depth.Sub(float64(len(queued)))
+			if len(q.order) > 0 {
+				// wake up another worker for the next chat
+				q.signal()
+			}
+			q.mu.Unlock()
+			return r, queued, true
+		}
+		q.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return Recipient{}, nil, false
+		case <-q.ready:
+		}
+	}
+}
+
+// done returns the chat's notifications queued meanwhile to the workers
+func (q *notificationQueue) done(r Recipient) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	delete(q.sending, r)
+	if len(q.queued[r]) > 0 {
+		q.order = append(q.order, r)
+		q.signal()
+	}
+}
+
+// coalesce joins consecutive notifications with the same parse mode into one message,
+// they're only sent silently if all of them are silent. Pinned notifications are kept on their own.
+func (b *Bot) coalesce(queued []notification) []notification {
+	var coalesced []notification
+	for _, n := range queued {
+		last := len(coalesced) - 1
+		if last < 0 || n.pinned(b) || coalesced[last].pinned(b) || coalesced[last].message.ParseMode != n.message.ParseMode {
+			coalesced = append(coalesced, n)
+			continue
+		}
+
+		prev := &coalesced[last]
+		prev.message.Text += "\n\n" + n.message.Text
+		prev.message.Silent = prev.message.Silent && n.message.Silent
+		prev.webhooks = append(prev.webhooks, n.webhooks...)
+		b.metrics.coalesced.Inc()
+	}
+	return coalesced
+}
+
+// sendNotifications sends the queued notifications with the bot's workers until ctx is done,
+// so chats that are slow to send to, like rate limited ones, don't hold back the others.
+func (b *Bot) sendNotifications(ctx context.Context) error {
+	workers := b.workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			b.sendQueued(ctx)
+		}()
+	}
+	wg.Wait()
+
+	// notifications still queued are lost when the bot stops
+	if dropped := b.queue.drain(); len(dropped) > 0 {
+		level.Warn(b.logger).Log("msg", "discarding queued notifications on shutdown", "count", len(dropped))
+		b.metrics.dropped.WithLabelValues("shutdown").Add(float64(len(dropped)))
+	}
+	return nil
+}
+
+// sendQueued sends the notifications of one chat after another until ctx is done
+func (b *Bot) sendQueued(ctx context.Context) {
+	for {
+		r, queued, ok := b.queue.pop(ctx)
+		if !ok {
+			return
+		}
+
+		now := time.Now()
+		for _, n := range queued {
+			b.metrics.queueDuration.Observe(now.Sub(n.queued).Seconds())
+		}
+		for _, n := range b.coalesce(queued) {
+			b.sendNotification(n)
+		}
+		b.queue.done(r)
+	}
+}
+
+// deliver sends the notification's message to its chat
+func (b *Bot) deliver(n notification) error {
+	if n.pinned(b) {
+		return b.sendPinned(n.chat, n.lang, n.webhooks[0], n.message)
+	}
+	_, err := b.sendMessage(n.chat.Recipient(), n.lang, n.message)
+	return err
+}
+
+// sendNotification sends the notification and remembers the alert groups delivered to the chat
+func (b *Bot) sendNotification(n notification) {
+	start := time.Now()
+	r := n.chat.Recipient()
+	defer func() {
+		for _, w := range n.webhooks {
+			b.deliveries.sent(r, w.GroupKey, w.Alerts)
+		}
+		b.metrics.sendDuration.WithLabelValues(n.chat.Type).Observe(time.Since(start).Seconds())
+	}()
+
+	err := b.deliver(n)
+	if to := migratedTo(err); to != 0 {
+		b.migrateChat(n.chat.ID, to)
+		n.chat.ID = to
+		n.chat.Type = botapi.ChatSupergroup
+		err = b.deliver(n)
+	}
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to send message to subscribed chat", "chat_id", n.chat.ID, "err", err)
+		b.metrics.notifications.WithLabelValues(n.chat.Type, "failure").Inc()
+		if isUnreachable(err) {
+			b.removeUnreachable(n.chat, err)
+		}
+		return
+	}
+	b.metrics.notifications.WithLabelValues(n.chat.Type, "success").Inc()
+
+	now := time.Now()
+	for _, w := range n.webhooks {
+		b.deliveries.delivered(n.chat.Recipient(), w.GroupKey, w.Alerts, n.chat.RepeatInterval, now)
+	}
+}
@@ -0,0 +1,111 @@
+package telegram
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotificationQueue(t *testing.T) {
+	depth := prometheus.NewGauge(prometheus.GaugeOpts{Name: "queued"})
+	q := newNotificationQueue(0, depth)
+
+	first := AugmentedChat{}
+	first.ID = 1
+	second := AugmentedChat{}
+	second.ID = 2
+
+	q.push(notification{chat: first, message: Message{Text: "a"}})
+	q.push(notification{chat: second, message: Message{Text: "b"}})
+	q.push(notification{chat: first, message: Message{Text: "c"}})
+	assert.Equal(t, float64(3), testutil.ToFloat64(depth))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r, queued, ok := q.pop(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, first.Recipient(), r)
+	assert.Len(t, queued, 2)
+	assert.Equal(t, "c", queued[1].message.Text)
+
+	// the first chat isn't returned again until it's done
+	q.push(notification{chat: first, message: Message{Text: "d"}})
+	r, queued, ok = q.pop(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, second.Recipient(), r)
+	assert.Len(t, queued, 1)
+
+	q.done(first.Recipient())
+	r, queued, ok = q.pop(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, first.Recipient(), r)
+	assert.Equal(t, "d", queued[0].message.Text)
+	assert.Equal(t, float64(0), testutil.ToFloat64(depth))
+
+	cancel()
+	_, _, ok = q.pop(ctx)
+	assert.False(t, ok)
+}
+
+func TestCoalesce(t *testing.T) {
+	b := &Bot{
+		pins:    &PinStore{},
+		metrics: testMetrics(t),
+	}
+
+	chat := AugmentedChat{}
+	pinned := AugmentedChat{PinCritical: true}
+
+	coalesced := b.coalesce([]notification{
+		{chat: chat, message: Message{Text: "a", ParseMode: ParseModeHTML, Silent: true}},
+		{chat: chat, message: Message{Text: "b", ParseMode: ParseModeHTML}},
+		{chat: chat, message: Message{Text: "c", ParseMode: ParseModeMarkdownV2}},
+		{chat: pinned, message: Message{Text: "d", ParseMode: ParseModeMarkdownV2}},
+		{chat: chat, message: Message{Text: "e", ParseMode: ParseModeMarkdownV2, Silent: true}},
+		{chat: chat, message: Message{Text: "f", ParseMode: ParseModeMarkdownV2, Silent: true}},
+	})
+	assert.Len(t, coalesced, 4)
+	assert.Equal(t, Message{Text: "a\n\nb", ParseMode: ParseModeHTML}, coalesced[0].message)
+	assert.Equal(t, "c", coalesced[1].message.Text)
+	assert.Equal(t, "d", coalesced[2].message.Text)
+	assert.Equal(t, Message{Text: "e\n\nf", ParseMode: ParseModeMarkdownV2, Silent: true}, coalesced[3].message)
+	assert.Equal(t, float64(2), testutil.ToFloat64(b.metrics.coalesced))
+}
+
+func TestNotificationQueueSize(t *testing.T) {
+	depth := prometheus.NewGauge(prometheus.GaugeOpts{Name: "queued"})
+	q := newNotificationQueue(2, depth)
+
+	chat := AugmentedChat{}
+	assert.True(t, q.push(notification{chat: chat, message: Message{Text: "a"}}))
+	assert.True(t, q.push(notification{chat: chat, message: Message{Text: "b"}}))
+	assert.False(t, q.push(notification{chat: chat, message: Message{Text: "c"}}))
+	assert.Equal(t, 2, q.len())
+
+	assert.Len(t, q.drain(), 2)
+	assert.Equal(t, 0, q.len())
+	assert.Equal(t, float64(0), testutil.ToFloat64(depth))
+	assert.True(t, q.push(notification{chat: chat, message: Message{Text: "d"}}))
+}
+
+func TestSendNotificationsDropsQueuedOnShutdown(t *testing.T) {
+	m := testMetrics(t)
+	b := &Bot{
+		logger:  log.NewNopLogger(),
+		metrics: m,
+		queue:   newNotificationQueue(0, m.queued),
+	}
+
+	chat := AugmentedChat{}
+	b.queue.push(notification{chat: chat, message: Message{Text: "a"}})
+	b.queue.push(notification{chat: chat, message: Message{Text: "b"}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.NoError(t, b.sendNotifications(ctx))
+	assert.Equal(t, 0, b.queue.len())
+	assert.Equal(t, float64(2), testutil.ToFloat64(m.dropped.WithLabelValues("shutdown")))
+}
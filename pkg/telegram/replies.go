@@ -0,0 +1,190 @@
+package telegram
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/alertmanager"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// Templates of the replies to commands, unlike notification templates chats can't choose them
+const (
+	TemplateChats    = "chats"
+	TemplateSilences = "silences"
+	TemplateStatus   = "status"
+)
+
+// replyTemplates are the names of the reply templates, sorted
+var replyTemplates = []string{TemplateChats, TemplateSilences, TemplateStatus}
+
+// defaultReplies are the built-in definitions of the reply templates,
+// template files can define their own to override them.
+var defaultReplies = map[string]string{
+	TemplateChats: `{{ define "telegram.chats" -}}
+{{ if .Chats }}{{ tr .Language "Currently these chats have subscribed:" }}
+{{ range .Chats }}
+{{ if .Group }}{{ .Name }}{{ else }}@{{ .Name }}{{ end }}{{ if .ThreadID }} ({{ tr $.Language "topic" }} {{ .ThreadID }}){{ end }} – {{ .Filters }}{{ if ne .Template "default" }} ({{ .Template }} {{ tr $.Language "template" }}){{ end }}
+{{ end }}{{ else }}{{ tr .Language "No chats have subscribed." }}{{ end }}
+{{- end }}
+`,
+	TemplateSilences: `{{ define "telegram.silences" -}}
+{{ range .Silences }}{{ .AlertName }}{{ if not .Resolved }} 🔕{{ end }}
+<code>{{ .Matchers }}</code>
+{{ if not .Resolved -}}
+<b>{{ tr $.Language "Started" }}:</b> {{ since .StartsAt $.Language }} {{ tr $.Language "ago" }}
+<b>{{ tr $.Language "Ends" }}:</b> {{ tr $.Language "in" }} {{ until .EndsAt $.Language }}
+{{- else -}}
+<b>{{ tr $.Language "Ended" }}:</b> {{ since .EndsAt $.Language }} {{ tr $.Language "ago" }}
+<b>{{ tr $.Language "Duration" }}:</b> {{ duration .StartsAt .EndsAt $.Language }}
+{{- end }}
+{{ if .Comment }}<i>{{ .Comment }}</i> – {{ .CreatedBy }}
+{{ end }}
+{{ else }}{{ tr $.Language "No silences right now." }}
+{{ end }}
+{{- end }}
+`,
+	TemplateStatus: `{{ define "telegram.status" -}}
+<b>AlertManager</b>
+{{ tr .Language "Version" }}: {{ .AlertmanagerVersion }}
+{{ tr .Language "Uptime" }}: {{ since .AlertmanagerStarted .Language }}
+<b>AlertManager Bot</b>
+{{ tr .Language "Version" }}: {{ .BotVersion }}
+{{ tr .Language "Uptime" }}: {{ since .BotStarted .Language }}
+{{- end }}
+`,
+}
+
+// ChatsData is rendered by the telegram.chats template
+type ChatsData struct {
+	Chats    []ChatData
+	Language string
+}
+
+// ChatData is a subscribed chat
+type ChatData struct {
+	ID int64
+	// Name is the title of group chats and the username of private chats
+	Name string
+	// ThreadID is the forum topic that subscribed, zero if the whole chat subscribed
+	ThreadID int
+	Group    bool
+	Filters  string
+	Template string
+}
+
+// SilencesData is rendered by the telegram.silences template
+type SilencesData struct {
+	Silences []SilenceData
+	Language string
+}
+
+// SilenceData is a silence with its alertname matcher separated from the other matchers
+type SilenceData struct {
+	ID        string
+	AlertName string
+	// Matchers are all matchers but alertname, like job="node" instance=~"db-.*"
+	Matchers  string
+	StartsAt  time.Time
+	EndsAt    time.Time
+	CreatedBy string
+	Comment   string
+	Resolved  bool
+}
+
+// StatusData is rendered by the telegram.status template
+type StatusData struct {
+	AlertmanagerVersion string
+	AlertmanagerStarted time.Time
+	BotVersion          string
+	BotStarted          time.Time
+	Language            string
+}
+
+// newChatsData returns the data of the telegram.chats template for the chats in the language
+func newChatsData(lang string, chats []AugmentedChat) ChatsData {
+	data := ChatsData{Chats: make([]ChatData, 0, len(chats)), Language: lang}
+	for _, chat := range chats {
+		name := chat.Username
+		if chat.IsGroupChat() {
+			name = chat.Title
+		}
+
+		tmpl := chat.Template
+		if tmpl == "" {
+			tmpl = DefaultTemplate
+		}
+
+		data.Chats = append(data.Chats, ChatData{
+			ID:       chat.ID,
+			Name:     name,
+			ThreadID: chat.ThreadID,
+			Group:    chat.IsGroupChat(),
+			Filters:  chat.GetFiltersAsString(),
+			Template: tmpl,
+		})
+	}
+	return data
+}
+
+// newSilencesData returns the data of the telegram.silences template for the silences in the language
+func newSilencesData(lang string, silences []types.Silence) SilencesData {
+	data := SilencesData{Silences: make([]SilenceData, 0, len(silences)), Language: lang}
+	for _, s := range silences {
+		silence := SilenceData{
+			ID:        s.ID,
+			StartsAt:  s.StartsAt,
+			EndsAt:    s.EndsAt,
+			CreatedBy: s.CreatedBy,
+			Comment:   s.Comment,
+			Resolved:  alertmanager.Resolved(s),
+		}
+
+		var matchers []string
+		for _, m := range s.Matchers {
+			if m.Name == "alertname" && !m.IsRegex {
+				silence.AlertName = m.Value
+				continue
+			}
+
+			op := "="
+			if m.IsRegex {
+				op = "=~"
+			}
+			matchers = append(matchers, fmt.Sprintf("%s%s%q", m.Name, op, m.Value))
+		}
+		silence.Matchers = strings.Join(matchers, " ")
+
+		data.Silences = append(data.Silences, silence)
+	}
+	return data
+}
+
+// sampleReplies returns sample data in the language for every reply template to validate them with
+func sampleReplies(lang string) map[string]interface{} {
+	now := time.Now()
+	return map[string]interface{}{
+		TemplateChats: ChatsData{Chats: []ChatData{
+			{ID: 1, Name: "admin", Filters: "Allowed ALL", Template: DefaultTemplate},
+			{ID: -2, Name: "Operations", Group: true, Filters: "severity=(critical)", Template: DefaultTemplate},
+			{ID: -3, Name: "On-call", ThreadID: 5, Group: true, Filters: "Allowed ALL", Template: DefaultTemplate},
+		}, Language: lang},
+		TemplateSilences: SilencesData{Silences: []SilenceData{
+			{ID: "1", AlertName: "SampleSilenced", Matchers: `instance="localhost:9100"`, StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour), CreatedBy: "admin", Comment: "Sample silence"},
+			{ID: "2", AlertName: "SampleExpired", StartsAt: now.Add(-2 * time.Hour), EndsAt: now.Add(-time.Hour), CreatedBy: "admin", Resolved: true},
+		}, Language: lang},
+		TemplateStatus: StatusData{
+			AlertmanagerVersion: "0.0.0",
+			AlertmanagerStarted: now.Add(-time.Hour),
+			BotVersion:          "0.0.0",
+			BotStarted:          now.Add(-time.Minute),
+			Language:            lang,
+		},
+	}
+}
+
+// Reply renders the data with the named reply template
+func (t *Templates) Reply(name string, data interface{}) (Message, error) {
+	return t.execute(name, data)
+}
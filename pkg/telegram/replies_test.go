@@ -0,0 +1,81 @@
+package telegram
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/botapi"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSilencesData(t *testing.T) {
+	starts := time.Now().Add(-time.Hour)
+	ends := time.Now().Add(time.Hour)
+
+	data := newSilencesData(LanguageEnglish, []types.Silence{{
+		ID: "1",
+		Matchers: types.Matchers{
+			{Name: "alertname", Value: "Disk_Full"},
+			{Name: "instance", Value: "db-.*", IsRegex: true},
+		},
+		StartsAt:  starts,
+		EndsAt:    ends,
+		CreatedBy: "admin",
+	}})
+
+	assert.Equal(t, SilencesData{Silences: []SilenceData{{
+		ID:        "1",
+		AlertName: "Disk_Full",
+		Matchers:  `instance=~"db-.*"`,
+		StartsAt:  starts,
+		EndsAt:    ends,
+		CreatedBy: "admin",
+	}}, Language: LanguageEnglish}, data)
+}
+
+func TestNewChatsData(t *testing.T) {
+	data := newChatsData(LanguageEnglish, []AugmentedChat{
+		{Chat: botapi.Chat{ID: 1, Type: "private", Username: "admin"}},
+		{Chat: botapi.Chat{ID: -2, Type: "group", Title: "Ops"}, Template: "short"},
+		{Chat: botapi.Chat{ID: -3, Type: "supergroup", Title: "On-call", IsForum: true}, ThreadID: 5},
+	})
+
+	assert.Equal(t, ChatsData{Chats: []ChatData{
+		{ID: 1, Name: "admin", Filters: "Allowed ALL", Template: DefaultTemplate},
+		{ID: -2, Name: "Ops", Group: true, Filters: "Allowed ALL", Template: "short"},
+		{ID: -3, Name: "On-call", ThreadID: 5, Group: true, Filters: "Allowed ALL", Template: DefaultTemplate},
+	}, Language: LanguageEnglish}, data)
+}
+
+func TestReplies(t *testing.T) {
+	tmpl, err := NewTemplates(&url.URL{}, "../../default.tmpl")
+	assert.NoError(t, err)
+
+	out, err := tmpl.Reply(TemplateSilences, SilencesData{Silences: []SilenceData{{
+		AlertName: "Disk_Full",
+		Matchers:  `mountpoint="<root>"`,
+		StartsAt:  time.Now().Add(-2 * time.Hour),
+		EndsAt:    time.Now().Add(-time.Hour),
+		Resolved:  true,
+	}}})
+	assert.NoError(t, err)
+	assert.Equal(t, ParseModeHTML, out.ParseMode)
+	assert.Contains(t, out.Text, "Disk_Full\n<code>mountpoint=&#34;&lt;root&gt;&#34;</code>\n<b>Ended:</b> ")
+
+	out, err = tmpl.Reply(TemplateSilences, SilencesData{})
+	assert.NoError(t, err)
+	assert.Equal(t, "No silences right now.\n", out.Text)
+
+	out, err = tmpl.Reply(TemplateSilences, SilencesData{Language: LanguageRussian})
+	assert.NoError(t, err)
+	assert.Equal(t, "Сейчас заглушек нет.\n", out.Text)
+
+	out, err = tmpl.Reply(TemplateChats, ChatsData{Chats: []ChatData{
+		{Name: "admin", Filters: "Allowed ALL", Template: DefaultTemplate},
+		{Name: "Ops", Group: true, Filters: "Allowed ALL", Template: "short"},
+	}})
+	assert.NoError(t, err)
+	assert.Equal(t, "Currently these chats have subscribed:\n\n@admin – Allowed ALL\n\nOps – Allowed ALL (short template)\n", out.Text)
+}
@@ -0,0 +1,128 @@
+package telegram
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/docker/libkv/store"
+)
+
+const (
+	telegramRoutesDirectory = "telegram/routes"
+
+	// routeChatPrefix addresses a single chat by its ID, like chat/-100123
+	routeChatPrefix = "chat/"
+)
+
+var routeNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
+// Route maps a named webhook route to the chats receiving its alerts
+type Route struct {
+	Name    string
+	ChatIDs []int64
+}
+
+// HasChat returns true if the chat receives the route's alerts
+func (r *Route) HasChat(id int64) bool {
+	for _, chatID := range r.ChatIDs {
+		if chatID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// AddChat adds the chat to the route if it's not part of it yet
+func (r *Route) AddChat(id int64) {
+	if !r.HasChat(id) {
+		r.ChatIDs = append(r.ChatIDs, id)
+	}
+}
+
+// RemoveChat removes the chat from the route
+func (r *Route) RemoveChat(id int64) {
+	ids := r.ChatIDs[:0]
+	for _, chatID := range r.ChatIDs {
+		if chatID != id {
+			ids = append(ids, chatID)
+		}
+	}
+	r.ChatIDs = ids
+}
+
+// ValidRouteName returns an error if the name can't be used as a named route
+func ValidRouteName(name string) error {
+	if !routeNameRegexp.MatchString(name) {
+		return fmt.Errorf("route name %q may only contain letters, digits, '_', '.' and '-'", name)
+	}
+	if name+"/" == routeChatPrefix {
+		return fmt.Errorf("route name %q is reserved", name)
+	}
+	return nil
+}
+
+// RouteStore writes the webhook routes to a libkv store backend
+type RouteStore struct {
+	kv store.Store
+}
+
+// NewRouteStore stores webhook routes in the provided kv backend
+func NewRouteStore(kv store.Store) (*RouteStore, error) {
+	return &RouteStore{kv: kv}, nil
+}
+
+// List all routes saved in the kv backend
+func (s *RouteStore) List() ([]Route, error) {
+	kvPairs, err := s.kv.List(telegramRoutesDirectory)
+	if err == store.ErrKeyNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var routes []Route
+	for _, kv := range kvPairs {
+		var r Route
+		if err := json.Unmarshal(kv.Value, &r); err != nil {
+			return nil, err
+		}
+		routes = append(routes, r)
+	}
+
+	return routes, nil
+}
+
+// Get a route by its name from the kv backend.
+// If there's no such route store.ErrKeyNotFound is returned.
+func (s *RouteStore) Get(name string) (Route, error) {
+	var r Route
+
+	kv, err := s.kv.Get(routeKey(name))
+	if err != nil {
+		return r, err
+	}
+
+	err = json.Unmarshal(kv.Value, &r)
+	return r, err
+}
+
+// Add a route to the kv backend, replacing a route with the same name
+func (s *RouteStore) Add(r Route) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	return s.kv.Put(routeKey(r.Name), b, nil)
+}
+
+// Remove a route from the kv backend
+func (s *RouteStore) Remove(r Route) error {
+	return s.kv.Delete(routeKey(r.Name))
+}
+
+func routeKey(name string) string {
+	return fmt.Sprintf("%s/%s", telegramRoutesDirectory, name)
+}
@@ -0,0 +1,60 @@
+package telegram
+
+import (
+	"testing"
+
+	"github.com/docker/libkv/store"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryRoutes is a BotRouteStore keeping the routes in memory
+type memoryRoutes map[string]Route
+
+func (m memoryRoutes) List() ([]Route, error) {
+	var routes []Route
+	for _, r := range m {
+		routes = append(routes, r)
+	}
+	return routes, nil
+}
+
+func (m memoryRoutes) Get(name string) (Route, error) {
+	r, ok := m[name]
+	if !ok {
+		return r, store.ErrKeyNotFound
+	}
+	return r, nil
+}
+
+func (m memoryRoutes) Add(r Route) error {
+	m[r.Name] = r
+	return nil
+}
+
+func (m memoryRoutes) Remove(r Route) error {
+	delete(m, r.Name)
+	return nil
+}
+
+func TestRouteChats(t *testing.T) {
+	r := Route{Name: "team-db"}
+	assert.False(t, r.HasChat(1))
+
+	r.AddChat(1)
+	r.AddChat(-100123)
+	r.AddChat(1)
+	assert.Equal(t, []int64{1, -100123}, r.ChatIDs)
+	assert.True(t, r.HasChat(-100123))
+
+	r.RemoveChat(1)
+	assert.Equal(t, []int64{-100123}, r.ChatIDs)
+	assert.False(t, r.HasChat(1))
+}
+
+func TestValidRouteName(t *testing.T) {
+	assert.NoError(t, ValidRouteName("team-db"))
+	assert.NoError(t, ValidRouteName("team_db.prod"))
+	assert.Error(t, ValidRouteName(""))
+	assert.Error(t, ValidRouteName("team/db"))
+	assert.Error(t, ValidRouteName("chat"))
+}
@@ -0,0 +1,361 @@
+package telegram
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+const (
+	// maxMessageLength is the maximum length of a message's text after entities parsing.
+	// Telegram counts the length in UTF-16 code units.
+	maxMessageLength = 4096
+	// partHeader is prepended to every part of a HTML message that has been split
+	partHeader = "<i>[%d/%d]</i>\n"
+	// partHeaderLength is reserved in every part for the part header
+	partHeaderLength = 16
+)
+
+// htmlToken is either a tag, an entity or a single character of a HTML message
+type htmlToken struct {
+	text string
+	// length is the number of UTF-16 code units the token is rendered as
+	length int
+	// tag is the name of the tag for tag tokens and empty otherwise
+	tag     string
+	closing bool
+}
+
+// tokenizeHTML splits a message formatted with Telegram's HTML subset into tokens
+// so that it can be split without breaking tags or entities.
+func tokenizeHTML(s string) []htmlToken {
+	tokens := make([]htmlToken, 0, len(s))
+
+	for i := 0; i < len(s); {
+		switch s[i] {
+		case '<':
+			if j := strings.IndexByte(s[i:], '>'); j > 0 {
+				inner := strings.TrimSpace(s[i+1 : i+j])
+				closing := strings.HasPrefix(inner, "/")
+				name := strings.ToLower(strings.Fields(strings.TrimPrefix(inner, "/") + " ")[0])
+
+				tokens = append(tokens, htmlToken{text: s[i : i+j+1], tag: name, closing: closing})
+				i += j + 1
+				continue
+			}
+		case '&':
+			if j := strings.IndexByte(s[i:], ';'); j > 1 && j <= 10 && !strings.ContainsAny(s[i+1:i+j], " \n&<") {
+				entity := s[i : i+j+1]
+				tokens = append(tokens, htmlToken{text: entity, length: utf16Length(html.UnescapeString(entity))})
+				i += j + 1
+				continue
+			}
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		length := 1
+		if r > 0xFFFF {
+			length = 2
+		}
+		tokens = append(tokens, htmlToken{text: s[i : i+size], length: length})
+		i += size
+	}
+
+	return tokens
+}
+
+// utf16Length returns the number of UTF-16 code units needed to encode s
+func utf16Length(s string) int {
+	return len(utf16.Encode([]rune(s)))
+}
+
+// messageLength returns the length of a HTML message the way Telegram counts it
+func messageLength(s string) int {
+	length := 0
+	for _, t := range tokenizeHTML(s) {
+		length += t.length
+	}
+	return length
+}
+
+// hrefRegexp matches the URL of a link's tag
+var hrefRegexp = regexp.MustCompile(`href\s*=\s*["']([^"']*)["']`)
+
+// plainText strips all tags and entities from a HTML message, the URLs of links follow their text like text (url)
+func plainText(s string) string {
+	var b strings.Builder
+	var href string
+	for _, t := range tokenizeHTML(s) {
+		switch {
+		case t.tag == "a" && !t.closing:
+			href = ""
+			if m := hrefRegexp.FindStringSubmatch(t.text); m != nil {
+				href = m[1]
+			}
+		case t.tag == "a" && href != "":
+			b.WriteString(" (" + href + ")")
+			href = ""
+		case t.tag == "":
+			b.WriteString(t.text)
+		}
+	}
+	return html.UnescapeString(b.String())
+}
+
+// breakPriority ranks splitting a message after the i-th token.
+// Alert boundaries (empty lines) are preferred over line breaks, line breaks over spaces,
+// and positions outside of any tag over positions inside of one.
+func breakPriority(tokens []htmlToken, i int, depth int) int {
+	priority := 0
+	switch tokens[i].text {
+	case "\n":
+		priority = 2
+		if i > 0 && tokens[i-1].text == "\n" {
+			priority = 3
+		}
+	case " ":
+		priority = 1
+	}
+
+	priority = priority * 2
+	if depth == 0 {
+		priority++
+	}
+	return priority
+}
+
+// splitMessage splits a HTML message into parts that are each at most limit long.
+// Tags open at a split are closed at the end of the part and opened again in the next part.
+func splitMessage(s string, limit int) []string {
+	if messageLength(s) <= limit {
+		return []string{s}
+	}
+
+	tokens := tokenizeHTML(s)
+
+	var parts []string
+	var open []htmlToken // tags open at the start of the current part
+
+	for start := 0; start < len(tokens); {
+		stack := append([]htmlToken(nil), open...)
+		end, endStack, priority := -1, stack, -1
+
+		length := 0
+		i := start
+		for ; i < len(tokens); i++ {
+			t := tokens[i]
+			if length+t.length > limit {
+				break
+			}
+			length += t.length
+
+			if t.tag != "" {
+				if !t.closing {
+					stack = append(stack, t)
+				} else if len(stack) > 0 && stack[len(stack)-1].tag == t.tag {
+					stack = stack[:len(stack)-1]
+				}
+			}
+
+			if p := breakPriority(tokens, i, len(stack)); p >= priority {
+				end, endStack, priority = i+1, append([]htmlToken(nil), stack...), p
+			}
+		}
+		if i == len(tokens) {
+			end, endStack = i, stack
+		}
+		if end <= start {
+			// not even a single token fits, which never happens for sane limits
+			end, endStack = start+1, open
+		}
+
+		parts = append(parts, renderPart(open, tokens[start:end], endStack))
+		open, start = endStack, end
+	}
+
+	return parts
+}
+
+// renderPart joins the tokens of a part, opening the tags that were open before
+// and closing all tags still open at its end.
+func renderPart(open []htmlToken, tokens []htmlToken, stack []htmlToken) string {
+	var body strings.Builder
+	for _, t := range tokens {
+		body.WriteString(t.text)
+	}
+
+	var b strings.Builder
+	for _, t := range open {
+		b.WriteString(t.text)
+	}
+	b.WriteString(strings.Trim(body.String(), "\n"))
+	for i := len(stack) - 1; i >= 0; i-- {
+		b.WriteString("</" + stack[i].tag + ">")
+	}
+
+	return b.String()
+}
+
+// markdownV2Markers start or end MarkdownV2 entities, longer markers first
+var markdownV2Markers = []string{"```", "`", "__", "||", "*", "_", "~", "](", "["}
+
+// markdownToken is either an entity marker, an escaped character or a single character of a MarkdownV2 message
+type markdownToken struct {
+	text string
+	// length is the number of UTF-16 code units the token is rendered as
+	length int
+	// depth is the number of entities open after the token
+	depth  int
+	marker bool
+}
+
+// tokenizeMarkdownV2 splits a message formatted with MarkdownV2 into tokens
+func tokenizeMarkdownV2(s string) []markdownToken {
+	tokens := make([]markdownToken, 0, len(s))
+	var open []string // markers of the open entities, the URL of a link is opened by (
+
+	for i := 0; i < len(s); {
+		top := ""
+		if len(open) > 0 {
+			top = open[len(open)-1]
+		}
+
+		var t markdownToken
+		switch {
+		case s[i] == '\\' && i+1 < len(s):
+			_, size := utf8.DecodeRuneInString(s[i+1:])
+			t = markdownToken{text: s[i : i+1+size], length: utf16Length(s[i+1 : i+1+size])}
+		case top == "```" || top == "`" || top == "(":
+			// only their closing marker ends code, pre and link URLs
+			closing := top
+			if top == "(" {
+				closing = ")"
+			}
+			if strings.HasPrefix(s[i:], closing) {
+				t.text, t.marker = closing, true
+				open = open[:len(open)-1]
+			}
+		default:
+			for _, m := range markdownV2Markers {
+				if !strings.HasPrefix(s[i:], m) {
+					continue
+				}
+				switch {
+				case m == "](" && top == "[":
+					open[len(open)-1] = "("
+				case m == "](":
+					continue
+				case m != "[" && m == top:
+					open = open[:len(open)-1]
+				default:
+					open = append(open, m)
+				}
+				t.text, t.marker = m, true
+				break
+			}
+		}
+		if t.text == "" {
+			_, size := utf8.DecodeRuneInString(s[i:])
+			t = markdownToken{text: s[i : i+size], length: utf16Length(s[i : i+size])}
+		}
+		if top == "(" {
+			// the URL of a link isn't part of the rendered text
+			t.length = 0
+		}
+
+		t.depth = len(open)
+		tokens = append(tokens, t)
+		i += len(t.text)
+	}
+
+	return tokens
+}
+
+// markdownBreakPriority ranks splitting a MarkdownV2 message after the i-th token.
+// Empty lines, line breaks and spaces outside of any entity are preferred,
+// splitting anywhere else outside of an entity is better than splitting inside of one.
+func markdownBreakPriority(tokens []markdownToken, i int) int {
+	if tokens[i].depth > 0 {
+		return 0
+	}
+	switch tokens[i].text {
+	case "\n":
+		if i > 0 && tokens[i-1].text == "\n" {
+			return 4
+		}
+		return 3
+	case " ":
+		return 2
+	}
+	return 1
+}
+
+// splitMarkdownV2 splits a MarkdownV2 message into parts that are each at most limit long.
+// Escaped characters are never split, entities only if a single one is longer than limit.
+func splitMarkdownV2(s string, limit int) []string {
+	tokens := tokenizeMarkdownV2(s)
+
+	length := 0
+	for _, t := range tokens {
+		length += t.length
+	}
+	if length <= limit {
+		return []string{s}
+	}
+
+	var parts []string
+	for start := 0; start < len(tokens); {
+		end, priority := -1, -1
+
+		length := 0
+		i := start
+		for ; i < len(tokens); i++ {
+			if length+tokens[i].length > limit {
+				break
+			}
+			length += tokens[i].length
+
+			if p := markdownBreakPriority(tokens, i); p >= priority {
+				end, priority = i+1, p
+			}
+		}
+		if i == len(tokens) {
+			end = i
+		}
+		if end <= start {
+			// not even a single token fits, which never happens for sane limits
+			end = start + 1
+		}
+
+		var part strings.Builder
+		for _, t := range tokens[start:end] {
+			part.WriteString(t.text)
+		}
+		parts = append(parts, strings.Trim(part.String(), "\n"))
+		start = end
+	}
+
+	return parts
+}
+
+// markdownV2PlainText strips all entity markers and escapes from a MarkdownV2 message,
+// the URLs of links follow their text like text (url)
+func markdownV2PlainText(s string) string {
+	var b strings.Builder
+	for _, t := range tokenizeMarkdownV2(s) {
+		switch {
+		case t.marker && t.text == "](":
+			b.WriteString(" (")
+		case t.marker && t.text == ")":
+			b.WriteString(")")
+		case t.marker:
+		case len(t.text) > 1 && t.text[0] == '\\':
+			b.WriteString(t.text[1:])
+		default:
+			b.WriteString(t.text)
+		}
+	}
+	return b.String()
+}
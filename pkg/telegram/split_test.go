@@ -0,0 +1,122 @@
+package telegram
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageLength(t *testing.T) {
+	assert.Equal(t, 0, messageLength(""))
+	assert.Equal(t, 4, messageLength("<b>FIRE</b>"))
+	assert.Equal(t, 5, messageLength("a &lt; b"))
+	// emojis outside of the BMP are two UTF-16 code units
+	assert.Equal(t, 5, messageLength("🔥 ok"))
+	assert.Equal(t, 2, messageLength("&#128293;"))
+}
+
+func TestPlainText(t *testing.T) {
+	assert.Equal(t, `FIRE <now> & "then"`, plainText(`<b>FIRE</b> &lt;now&gt; &amp; &quot;then&quot;`))
+}
+
+func TestSplitMessage(t *testing.T) {
+	alert := "<b>FIRING</b>\n<b>Fire</b>\nSomething is on fire\n\n"
+
+	testcases := []struct {
+		name     string
+		message  string
+		limit    int
+		expected []string
+	}{
+		{
+			name:     "Short",
+			message:  alert,
+			limit:    100,
+			expected: []string{alert},
+		},
+		{
+			name:    "AlertBoundaries",
+			message: strings.Repeat(alert, 3),
+			limit:   80,
+			expected: []string{
+				"<b>FIRING</b>\n<b>Fire</b>\nSomething is on fire\n\n<b>FIRING</b>\n<b>Fire</b>\nSomething is on fire",
+				"<b>FIRING</b>\n<b>Fire</b>\nSomething is on fire",
+			},
+		},
+		{
+			name:     "ReopenTags",
+			message:  "<pre>aaaa\nbbbb\ncccc</pre>",
+			limit:    10,
+			expected: []string{"<pre>aaaa\nbbbb</pre>", "<pre>cccc</pre>"},
+		},
+		{
+			name:     "KeepEntities",
+			message:  "&lt;&lt;&lt;&lt;&lt;",
+			limit:    2,
+			expected: []string{"&lt;&lt;", "&lt;&lt;", "&lt;"},
+		},
+		{
+			name:     "KeepSurrogatePairs",
+			message:  "🔥🔥🔥",
+			limit:    3,
+			expected: []string{"🔥", "🔥", "🔥"},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			parts := splitMessage(tc.message, tc.limit)
+			assert.Equal(t, tc.expected, parts)
+			for _, part := range parts {
+				assert.True(t, messageLength(part) <= tc.limit)
+			}
+		})
+	}
+}
+
+func TestSplitMarkdownV2(t *testing.T) {
+	testcases := []struct {
+		name     string
+		message  string
+		limit    int
+		expected []string
+	}{
+		{
+			name:     "Short",
+			message:  `*FIRING* db\-1`,
+			limit:    100,
+			expected: []string{`*FIRING* db\-1`},
+		},
+		{
+			name:     "AlertBoundaries",
+			message:  "*Fire* a\n\n*Fire* b\n\n*Fire* c",
+			limit:    16,
+			expected: []string{"*Fire* a\n\n*Fire* b", "*Fire* c"},
+		},
+		{
+			name:     "OutsideOfEntities",
+			message:  "*bold text* _italic text_",
+			limit:    12,
+			expected: []string{"*bold text* ", "_italic text_"},
+		},
+		{
+			name:     "OutsideOfLinks",
+			message:  "see [the alert](http://x/?a=1 b) now",
+			limit:    10,
+			expected: []string{"see ", "[the alert](http://x/?a=1 b) ", "now"},
+		},
+		{
+			name:     "Escapes",
+			message:  `ab\.\.\.`,
+			limit:    3,
+			expected: []string{`ab\.`, `\.\.`},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, splitMarkdownV2(tc.message, tc.limit))
+		})
+	}
+}
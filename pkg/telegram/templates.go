@@ -0,0 +1,320 @@
+package telegram
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	tmplhtml "html/template"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	tmpltext "text/template"
+	"time"
+
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+)
+
+const (
+	// DefaultTemplate is the notification template used by chats that haven't chosen one
+	DefaultTemplate = "default"
+	// templatePrefix is the prefix of all notification templates chats can choose from
+	templatePrefix = "telegram."
+	// parseModeSuffix names the template choosing the parse mode of a notification template,
+	// like {{ define "telegram.short.parse_mode" }}MarkdownV2{{ end }}
+	parseModeSuffix = ".parse_mode"
+	// silentSuffix names the template deciding if a notification is sent without sound,
+	// like {{ define "telegram.short.silent" }}{{ ne .CommonLabels.severity "critical" }}{{ end }}
+	silentSuffix = ".silent"
+)
+
+// Message is a notification rendered for Telegram
+type Message struct {
+	Text      string
+	ParseMode string
+	// Silent messages are sent without notification sound
+	Silent bool
+}
+
+// NotificationData is rendered by notification templates,
+// Alertmanager's webhook data with the language of the chat it's sent to.
+type NotificationData struct {
+	*template.Data
+	Language string
+}
+
+// alertmanagerTemplates are the helpers of Alertmanager's built-in templates,
+// which template.FromGlobs parses before the files.
+const alertmanagerTemplates = `
+{{ define "__alertmanager" }}AlertManager{{ end }}
+{{ define "__alertmanagerURL" }}{{ .ExternalURL }}/#/alerts?receiver={{ .Receiver }}{{ end }}
+
+{{ define "__subject" }}[{{ .Status | toUpper }}{{ if eq .Status "firing" }}:{{ .Alerts.Firing | len }}{{ end }}] {{ .GroupLabels.SortedPairs.Values | join " " }} {{ if gt (len .CommonLabels) (len .GroupLabels) }}({{ with .CommonLabels.Remove .GroupLabels.Names }}{{ .Values | join " " }}{{ end }}){{ end }}{{ end }}
+{{ define "__description" }}{{ end }}
+
+{{ define "__text_alert_list" }}{{ range . }}Labels:
+{{ range .Labels.SortedPairs }} - {{ .Name }} = {{ .Value }}
+{{ end }}Annotations:
+{{ range .Annotations.SortedPairs }} - {{ .Name }} = {{ .Value }}
+{{ end }}Source: {{ .GeneratorURL }}
+{{ end }}{{ end }}
+`
+
+// Templates bundles the templates messages are rendered with
+// and the names and parse modes of the notification templates chats can choose from.
+// The Alertmanager template only assembles the data, its functions can't be extended without changing them globally.
+type Templates struct {
+	*template.Template
+	text  *tmpltext.Template
+	html  *tmplhtml.Template
+	names []string
+	modes map[string]string
+	// silent are the notification templates with a silent template
+	silent map[string]bool
+	// replies are the built-in definitions of the reply templates the files don't define
+	replies string
+}
+
+// NewTemplates parses the template files matching the globs.
+// Every template defined as telegram.<name> can be chosen by chats with /template <name>.
+func NewTemplates(externalURL *url.URL, paths ...string) (*Templates, error) {
+	funcs := map[string]interface{}{}
+	for name, f := range template.DefaultFuncs {
+		funcs[name] = f
+	}
+	for name, f := range templateFuncs {
+		funcs[name] = f
+	}
+
+	text, err := tmpltext.New("").Option("missingkey=zero").Funcs(funcs).Parse(alertmanagerTemplates)
+	if err != nil {
+		return nil, err
+	}
+	htmlTmpl, err := tmplhtml.New("").Option("missingkey=zero").Funcs(funcs).Parse(alertmanagerTemplates)
+	if err != nil {
+		return nil, err
+	}
+	for _, tp := range paths {
+		// like template.FromGlobs globs matching no files are allowed
+		p, err := filepath.Glob(tp)
+		if err != nil {
+			return nil, err
+		}
+		if len(p) > 0 {
+			if text, err = text.ParseGlob(tp); err != nil {
+				return nil, err
+			}
+			if htmlTmpl, err = htmlTmpl.ParseGlob(tp); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	tmpl := &Templates{Template: &template.Template{ExternalURL: externalURL}, text: text, html: htmlTmpl}
+
+	var names []string
+	modes := map[string]string{}
+	silent := map[string]bool{}
+	for _, t := range text.Templates() {
+		if !strings.HasPrefix(t.Name(), templatePrefix) {
+			continue
+		}
+
+		name := strings.TrimPrefix(t.Name(), templatePrefix)
+		if strings.HasSuffix(name, silentSuffix) {
+			silent[strings.TrimSuffix(name, silentSuffix)] = true
+			continue
+		}
+		if !strings.HasSuffix(name, parseModeSuffix) {
+			if !contains(replyTemplates, name) {
+				names = append(names, name)
+			}
+			continue
+		}
+
+		mode, err := tmpl.ExecuteTextString(fmt.Sprintf(`{{ template "%s" . }}`, t.Name()), nil)
+		if err != nil {
+			return nil, err
+		}
+		mode = strings.TrimSpace(mode)
+		if !validParseMode(mode) {
+			return nil, fmt.Errorf("template %s has unknown parse mode %q, choose from %s, %s or %s",
+				t.Name(), mode, ParseModeHTML, ParseModeMarkdownV2, ParseModePlain)
+		}
+		modes[strings.TrimSuffix(name, parseModeSuffix)] = mode
+	}
+	sort.Strings(names)
+
+	if text.Lookup(templatePrefix+DefaultTemplate) == nil {
+		return nil, fmt.Errorf("template %s%s is not defined, see default.tmpl", templatePrefix, DefaultTemplate)
+	}
+
+	// Reply templates the files don't define fall back to the built-in ones
+	var replies string
+	for _, name := range replyTemplates {
+		if text.Lookup(templatePrefix+name) == nil {
+			replies += strings.TrimSpace(defaultReplies[name])
+		}
+	}
+
+	tmpl.names, tmpl.modes, tmpl.silent, tmpl.replies = names, modes, silent, replies
+	if err := tmpl.Validate(); err != nil {
+		return nil, err
+	}
+
+	return tmpl, nil
+}
+
+// ExecuteTextString renders the text, which may use the templates of the files
+func (t *Templates) ExecuteTextString(text string, data interface{}) (string, error) {
+	if text == "" {
+		return "", nil
+	}
+	tmpl, err := t.text.Clone()
+	if err != nil {
+		return "", err
+	}
+	if tmpl, err = tmpl.New("").Parse(text); err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, data)
+	return buf.String(), err
+}
+
+// ExecuteHTMLString renders the text escaping values for HTML, it may use the templates of the files
+func (t *Templates) ExecuteHTMLString(text string, data interface{}) (string, error) {
+	if text == "" {
+		return "", nil
+	}
+	tmpl, err := t.html.Clone()
+	if err != nil {
+		return "", err
+	}
+	if tmpl, err = tmpl.New("").Parse(text); err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, data)
+	return buf.String(), err
+}
+
+// SampleData returns a webhook with a firing and a resolved sample alert
+// to test templates with.
+func (t *Templates) SampleData() *template.Data {
+	now := time.Now()
+	return t.Data("sample", model.LabelSet{"alertname": "Sample"},
+		&types.Alert{Alert: model.Alert{
+			Labels:       model.LabelSet{"alertname": "SampleFiring", "severity": "critical", "instance": "localhost:9100"},
+			Annotations:  model.LabelSet{"summary": "Sample alert", "description": "Sample firing alert"},
+			StartsAt:     now.Add(-time.Hour),
+			GeneratorURL: "http://localhost:9090/graph",
+		}},
+		&types.Alert{Alert: model.Alert{
+			Labels:       model.LabelSet{"alertname": "SampleResolved", "severity": "warning", "instance": "localhost:9100"},
+			Annotations:  model.LabelSet{"summary": "Sample alert", "description": "Sample resolved alert"},
+			StartsAt:     now.Add(-2 * time.Hour),
+			EndsAt:       now.Add(-time.Minute),
+			GeneratorURL: "http://localhost:9090/graph",
+		}},
+	)
+}
+
+// Validate renders every notification template with the sample data and every reply template
+// with sample replies to catch errors that only show when templates are executed.
+func (t *Templates) Validate() error {
+	data := t.SampleData()
+	for _, name := range t.names {
+		if _, err := t.Notification(name, DefaultLanguage, data); err != nil {
+			return fmt.Errorf("template %s%s is invalid: %v", templatePrefix, name, err)
+		}
+	}
+	for name, data := range sampleReplies(DefaultLanguage) {
+		if _, err := t.Reply(name, data); err != nil {
+			return fmt.Errorf("template %s%s is invalid: %v", templatePrefix, name, err)
+		}
+	}
+	return nil
+}
+
+// Names returns the names of all notification templates, sorted
+func (t *Templates) Names() []string {
+	return t.names
+}
+
+// Has returns true if a notification template with the name exists
+func (t *Templates) Has(name string) bool {
+	return contains(t.names, name)
+}
+
+// ParseMode returns the parse mode of the named template, HTML if it didn't choose one
+func (t *Templates) ParseMode(name string) string {
+	if mode, ok := t.modes[name]; ok {
+		return mode
+	}
+	return ParseModeHTML
+}
+
+// Notification renders the data in the language with the named notification template,
+// falling back to the default template if there's no template with that name.
+// HTML templates escape values for HTML, other templates need to escape them on their own.
+// The notification is silent if the template's silent template renders true.
+func (t *Templates) Notification(name string, lang string, data *template.Data) (Message, error) {
+	if !t.Has(name) {
+		name = DefaultTemplate
+	}
+	notification := NotificationData{Data: data, Language: lang}
+
+	m, err := t.execute(name, notification)
+	if err != nil || !t.silent[name] {
+		return m, err
+	}
+
+	silent, err := t.ExecuteTextString(fmt.Sprintf(`{{ template "%s%s%s" . }}`, templatePrefix, name, silentSuffix), notification)
+	if err != nil {
+		return m, err
+	}
+	if silent = strings.TrimSpace(silent); silent != "" {
+		if m.Silent, err = strconv.ParseBool(silent); err != nil {
+			return m, fmt.Errorf("template %s%s%s returned %q instead of true or false", templatePrefix, name, silentSuffix, silent)
+		}
+	}
+	return m, nil
+}
+
+// execute renders the data with the template telegram.<name> in its parse mode
+func (t *Templates) execute(name string, data interface{}) (Message, error) {
+	text := t.replies + fmt.Sprintf(`{{ template "%s%s" . }}`, templatePrefix, name)
+	mode := t.ParseMode(name)
+
+	var out string
+	var err error
+	if mode == ParseModeHTML {
+		out, err = t.ExecuteHTMLString(text, data)
+	} else {
+		out, err = t.ExecuteTextString(text, data)
+	}
+
+	return Message{Text: out, ParseMode: mode}, err
+}
+
+// Alerts renders the alerts in the language with the named notification template as HTML,
+// templates with other parse modes lose their formatting.
+func (t *Templates) Alerts(name string, lang string, alerts ...*types.Alert) (string, error) {
+	m, err := t.Notification(name, lang, t.Data("default", nil, alerts...))
+	if err != nil {
+		return "", err
+	}
+	if m.ParseMode == ParseModeHTML {
+		return m.Text, nil
+	}
+	return html.EscapeString(toPlainText(m.Text, m.ParseMode)), nil
+}
+
+func contains(list []string, s string) bool {
+	i := sort.SearchStrings(list, s)
+	return i < len(list) && list[i] == s
+}
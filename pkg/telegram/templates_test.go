@@ -0,0 +1,196 @@
+package telegram
+
+import (
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/prometheus/alertmanager/template"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplates(t *testing.T) {
+	tmpl, err := NewTemplates(&url.URL{}, "../../default.tmpl")
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"default", "detailed", "short"}, tmpl.Names())
+	assert.True(t, tmpl.Has("short"))
+	assert.False(t, tmpl.Has("missing"))
+
+	data := &template.Data{Alerts: template.Alerts{{
+		Status: "firing",
+		Labels: template.KV{"alertname": "Fire", "severity": "critical"},
+	}}}
+
+	out, err := tmpl.Notification("short", LanguageEnglish, data)
+	assert.NoError(t, err)
+	assert.Equal(t, Message{Text: "\n🔥 <b>Fire</b> (critical)\n\n", ParseMode: ParseModeHTML}, out)
+
+	fallback, err := tmpl.Notification("missing", LanguageEnglish, data)
+	assert.NoError(t, err)
+	def, err := tmpl.Notification(DefaultTemplate, LanguageEnglish, data)
+	assert.NoError(t, err)
+	assert.Equal(t, def, fallback)
+
+	_, err = NewTemplates(&url.URL{}, "missing.tmpl")
+	assert.Error(t, err)
+
+	dir, err := ioutil.TempDir("", "templates")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "custom.tmpl")
+	err = ioutil.WriteFile(path, []byte(`{{ define "telegram.default" }}{{ .Status }}{{ end }}`), 0644)
+	assert.NoError(t, err)
+
+	custom, err := NewTemplates(&url.URL{}, path)
+	assert.NoError(t, err)
+	out, err = custom.Reply(TemplateChats, ChatsData{Language: LanguageEnglish})
+	assert.NoError(t, err)
+	assert.Equal(t, "No chats have subscribed.", out.Text)
+
+	status := filepath.Join(dir, "status.tmpl")
+	err = ioutil.WriteFile(status, []byte(`{{ define "telegram.status" }}{{ .BotVersion }}{{ end }}`), 0644)
+	assert.NoError(t, err)
+
+	custom, err = NewTemplates(&url.URL{}, path, status)
+	assert.NoError(t, err)
+	out, err = custom.Reply(TemplateStatus, StatusData{BotVersion: "v1"})
+	assert.NoError(t, err)
+	assert.Equal(t, "v1", out.Text)
+
+	_, err = NewTemplates(&url.URL{}, status)
+	assert.EqualError(t, err, "template telegram.default is not defined, see default.tmpl")
+}
+
+func TestTemplatesValidate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "templates")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "broken.tmpl")
+	err = ioutil.WriteFile(path, []byte(`{{ define "telegram.default" }}{{ .Missing }}{{ end }}`), 0644)
+	assert.NoError(t, err)
+
+	_, err = NewTemplates(&url.URL{}, "../../default.tmpl", path)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "template telegram.default is invalid")
+	assert.Contains(t, err.Error(), "can't evaluate field Missing")
+}
+
+func TestTemplatesParseMode(t *testing.T) {
+	dir, err := ioutil.TempDir("", "templates")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "modes.tmpl")
+	err = ioutil.WriteFile(path, []byte(`
+{{ define "telegram.default" }}<b>{{ .Status }}</b>{{ end }}
+{{ define "telegram.markdown" }}*{{ escapeMarkdownV2 .CommonLabels.job }}* <{{ .Status }}>{{ end }}
+{{ define "telegram.markdown.parse_mode" }} MarkdownV2 {{ end }}
+{{ define "telegram.plain" }}<{{ .Status }}>{{ end }}
+{{ define "telegram.plain.parse_mode" }}plain{{ end }}
+`), 0644)
+	assert.NoError(t, err)
+
+	tmpl, err := NewTemplates(&url.URL{}, "../../default.tmpl", path)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"default", "detailed", "markdown", "plain", "short"}, tmpl.Names())
+
+	data := &template.Data{Status: "firing", CommonLabels: template.KV{"job": "node_exporter"}}
+
+	out, err := tmpl.Notification("markdown", LanguageEnglish, data)
+	assert.NoError(t, err)
+	assert.Equal(t, Message{Text: `*node\_exporter* <firing>`, ParseMode: ParseModeMarkdownV2}, out)
+
+	out, err = tmpl.Notification("plain", LanguageEnglish, data)
+	assert.NoError(t, err)
+	assert.Equal(t, Message{Text: "<firing>", ParseMode: ParseModePlain}, out)
+
+	err = ioutil.WriteFile(path, []byte(`
+{{ define "telegram.default" }}{{ .Status }}{{ end }}
+{{ define "telegram.default.parse_mode" }}Markdown{{ end }}
+`), 0644)
+	assert.NoError(t, err)
+
+	_, err = NewTemplates(&url.URL{}, "../../default.tmpl", path)
+	assert.EqualError(t, err, `template telegram.default.parse_mode has unknown parse mode "Markdown", choose from HTML, MarkdownV2 or plain`)
+}
+
+func TestTemplatesSilent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "templates")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "silent.tmpl")
+	err = ioutil.WriteFile(path, []byte(`
+{{ define "telegram.quiet" }}{{ .Status }}{{ end }}
+{{ define "telegram.quiet.silent" }} {{ ne .CommonLabels.severity "critical" }} {{ end }}
+`), 0644)
+	assert.NoError(t, err)
+
+	tmpl, err := NewTemplates(&url.URL{}, "../../default.tmpl", path)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"default", "detailed", "quiet", "short"}, tmpl.Names())
+
+	out, err := tmpl.Notification("quiet", LanguageEnglish, &template.Data{Status: "firing", CommonLabels: template.KV{"severity": "warning"}})
+	assert.NoError(t, err)
+	assert.Equal(t, Message{Text: "firing", ParseMode: ParseModeHTML, Silent: true}, out)
+
+	out, err = tmpl.Notification("quiet", LanguageEnglish, &template.Data{Status: "firing", CommonLabels: template.KV{"severity": "critical"}})
+	assert.NoError(t, err)
+	assert.False(t, out.Silent)
+
+	err = ioutil.WriteFile(path, []byte(`
+{{ define "telegram.quiet" }}{{ .Status }}{{ end }}
+{{ define "telegram.quiet.silent" }}maybe{{ end }}
+`), 0644)
+	assert.NoError(t, err)
+
+	_, err = NewTemplates(&url.URL{}, "../../default.tmpl", path)
+	assert.EqualError(t, err, `template telegram.quiet is invalid: template telegram.quiet.silent returned "maybe" instead of true or false`)
+}
+
+func TestTemplatesEscapeHTML(t *testing.T) {
+	dir, err := ioutil.TempDir("", "templates")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "escape.tmpl")
+	err = ioutil.WriteFile(path, []byte(`{{ define "telegram.default" }}{{ escapeHTML "a & <b>" }} {{ printf "<b>%s</b>" (escapeHTML "c & d") | safeHtml }}{{ end }}`), 0644)
+	assert.NoError(t, err)
+
+	tmpl, err := NewTemplates(&url.URL{}, path)
+	assert.NoError(t, err)
+
+	out, err := tmpl.Notification(DefaultTemplate, LanguageEnglish, &template.Data{})
+	assert.NoError(t, err)
+	assert.Equal(t, "a &amp; &lt;b&gt; <b>c &amp; d</b>", out.Text)
+}
+
+func TestTemplatesFuncsAreLocal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "templates")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "subject.tmpl")
+	err = ioutil.WriteFile(path, []byte(`{{ define "telegram.default" }}{{ template "__subject" . }}{{ escapeHTML "&" }}{{ end }}`), 0644)
+	assert.NoError(t, err)
+
+	tmpl, err := NewTemplates(&url.URL{}, path)
+	assert.NoError(t, err)
+
+	// reloading templates while others render them doesn't change Alertmanager's global functions
+	_, ok := template.DefaultFuncs["escapeHTML"]
+	assert.False(t, ok)
+
+	out, err := tmpl.Notification(DefaultTemplate, LanguageEnglish, &template.Data{
+		Status:      "firing",
+		Alerts:      template.Alerts{{Status: "firing"}},
+		GroupLabels: template.KV{"alertname": "Fire"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "[FIRING:1] Fire &amp;", out.Text)
+}
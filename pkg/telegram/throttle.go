@@ -0,0 +1,144 @@
+package telegram
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/botapi"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/time/rate"
+)
+
+// Telegram's limits, see https://core.telegram.org/bots/faq#my-bot-is-hitting-limits-how-do-i-avoid-this
+var (
+	// globalLimit is about 30 messages per second to all chats
+	globalLimit = rate.Limit(30)
+	// privateChatLimit is about one message per second to a private chat
+	privateChatLimit = rate.Every(time.Second)
+	// groupChatLimit is about 20 messages per minute to a group
+	groupChatLimit = rate.Every(3 * time.Second)
+	// chatBurst are the messages sent to a chat at once, like the parts of a long message
+	chatBurst = 3
+)
+
+// maxRetries are the retries of a request Telegram rejected with retry_after
+const maxRetries = 3
+
+// throttle limits the requests sent to Telegram globally and by chat with token buckets,
+// and holds back requests to chats Telegram asked to retry after a while.
+type throttle struct {
+	global *rate.Limiter
+
+	mu    sync.Mutex
+	chats map[int64]*rate.Limiter
+	// blocked is when requests to the chats may be sent again, after Telegram answered with retry_after
+	blocked map[int64]time.Time
+
+	throttled *prometheus.CounterVec
+
+	// ctx is canceled by stop to end the waits of all requests
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// newThrottle returns a throttle counting the requests it held back by reason
+func newThrottle(throttled *prometheus.CounterVec) *throttle {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &throttle{
+		global:    rate.NewLimiter(globalLimit, int(globalLimit)),
+		chats:     map[int64]*rate.Limiter{},
+		blocked:   map[int64]time.Time{},
+		throttled: throttled,
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+// stop ends the waits of all requests, so requests that would have to wait return without being sent
+func (t *throttle) stop() {
+	if t != nil {
+		t.cancel()
+	}
+}
+
+// chat returns the limiter of the chat, groups have negative IDs
+func (t *throttle) chat(chatID int64) *rate.Limiter {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	l, ok := t.chats[chatID]
+	if !ok {
+		limit := privateChatLimit
+		if chatID < 0 {
+			limit = groupChatLimit
+		}
+		l = rate.NewLimiter(limit, chatBurst)
+		t.chats[chatID] = l
+	}
+	return l
+}
+
+// wait blocks until a request may be sent to the chat or the throttle is stopped
+func (t *throttle) wait(chatID int64) error {
+	t.mu.Lock()
+	until := t.blocked[chatID]
+	t.mu.Unlock()
+
+	if d := time.Until(until); d > 0 {
+		t.throttled.WithLabelValues("retry_after").Inc()
+		if err := t.sleep(d); err != nil {
+			return err
+		}
+	}
+	if r := t.chat(chatID).Reserve(); r.Delay() > 0 {
+		t.throttled.WithLabelValues("chat").Inc()
+		if err := t.sleep(r.Delay()); err != nil {
+			r.Cancel()
+			return err
+		}
+	}
+	if r := t.global.Reserve(); r.Delay() > 0 {
+		t.throttled.WithLabelValues("global").Inc()
+		if err := t.sleep(r.Delay()); err != nil {
+			r.Cancel()
+			return err
+		}
+	}
+	return nil
+}
+
+// sleep waits for d, unless the throttle is stopped before
+func (t *throttle) sleep(d time.Duration) error {
+	select {
+	case <-time.After(d):
+		return nil
+	case <-t.ctx.Done():
+		return t.ctx.Err()
+	}
+}
+
+// do sends the request to the chat once it may be sent.
+// Requests Telegram rejects with retry_after are sent again after that time.
+// Requests still waiting when the throttle is stopped aren't sent and return context.Canceled.
+func (t *throttle) do(chatID int64, request func() error) error {
+	if t == nil {
+		return request()
+	}
+
+	for retries := 0; ; retries++ {
+		if err := t.wait(chatID); err != nil {
+			return err
+		}
+
+		err := request()
+		apiErr, ok := err.(*botapi.Error)
+		if !ok || apiErr.RetryAfter <= 0 || retries == maxRetries {
+			return err
+		}
+
+		t.mu.Lock()
+		t.blocked[chatID] = time.Now().Add(time.Duration(apiErr.RetryAfter) * time.Second)
+		t.mu.Unlock()
+	}
+}
@@ -0,0 +1,69 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/metalmatze/alertmanager-bot/pkg/botapi"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThrottleRetryAfter(t *testing.T) {
+	throttled := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "throttled"}, []string{"reason"})
+	th := newThrottle(throttled)
+
+	requests := 0
+	start := time.Now()
+	err := th.do(1, func() error {
+		requests++
+		if requests == 1 {
+			return &botapi.Error{Code: 429, Description: "Too Many Requests: retry after 1", RetryAfter: 1}
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, requests)
+	assert.True(t, time.Since(start) >= time.Second)
+	assert.Equal(t, float64(1), testutil.ToFloat64(throttled.WithLabelValues("retry_after")))
+
+	requests = 0
+	err = th.do(2, func() error {
+		requests++
+		return errors.New("bad request")
+	})
+	assert.EqualError(t, err, "bad request")
+	assert.Equal(t, 1, requests)
+}
+
+func TestThrottleChatLimit(t *testing.T) {
+	throttled := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "throttled"}, []string{"reason"})
+	th := newThrottle(throttled)
+
+	for i := 0; i < chatBurst; i++ {
+		assert.NoError(t, th.do(1, func() error { return nil }))
+	}
+	assert.Equal(t, float64(0), testutil.ToFloat64(throttled.WithLabelValues("chat")))
+
+	assert.NoError(t, th.do(1, func() error { return nil }))
+	assert.Equal(t, float64(1), testutil.ToFloat64(throttled.WithLabelValues("chat")))
+}
+
+func TestThrottleStop(t *testing.T) {
+	throttled := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "throttled"}, []string{"reason"})
+	th := newThrottle(throttled)
+
+	requests := 0
+	time.AfterFunc(100*time.Millisecond, th.stop)
+	start := time.Now()
+	err := th.do(1, func() error {
+		requests++
+		return &botapi.Error{Code: 429, Description: "Too Many Requests: retry after 60", RetryAfter: 60}
+	})
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, 1, requests)
+	assert.True(t, time.Since(start) < time.Second)
+}
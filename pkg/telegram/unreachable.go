@@ -0,0 +1,172 @@
+package telegram
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/metalmatze/alertmanager-bot/pkg/botapi"
+)
+
+// unreachableErrors are the descriptions of errors that mean the bot can't send to a chat ever again.
+// Others, like missing rights to send messages in a restricted group, can be temporary.
+var unreachableErrors = []string{
+	"bot was blocked by the user",
+	"bot was kicked",
+	"user is deactivated",
+	"chat not found",
+	"message thread not found",
+}
+
+// isUnreachable returns true if the error means the bot can't send to the chat anymore,
+// like users who blocked the bot, groups it was removed from or deleted forum topics.
+func isUnreachable(err error) bool {
+	apiErr, ok := err.(*botapi.Error)
+	if !ok || (apiErr.Code != http.StatusForbidden && apiErr.Code != http.StatusBadRequest) {
+		return false
+	}
+	for _, description := range unreachableErrors {
+		if strings.Contains(apiErr.Description, description) {
+			return true
+		}
+	}
+	return false
+}
+
+// migratedTo returns the new ID of a group upgraded to a supergroup, if sending to it failed because of that
+func migratedTo(err error) int64 {
+	if apiErr, ok := err.(*botapi.Error); ok {
+		return apiErr.MigrateToChatID
+	}
+	return 0
+}
+
+// removeUnreachable removes the subscription of a chat the bot can't send to anymore and tells the admins
+func (b *Bot) removeUnreachable(chat AugmentedChat, err error) {
+	if err := b.chats.Remove(chat); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to remove unreachable chat", "chat_id", chat.ID, "err", err)
+		return
+	}
+	level.Info(b.logger).Log("msg", "removed unreachable chat", "chat_id", chat.ID, "thread_id", chat.ThreadID, "err", err)
+
+	b.notifyAdmins(func(lang string) string {
+		return tr(lang, "I removed chat %s from the subscribed chats, I can't send to it anymore: %s", chatTitle(lang, chat), err)
+	})
+}
+
+// migrateChat moves the subscriptions, routes and pins of a group upgraded to a supergroup to the supergroup's ID
+func (b *Bot) migrateChat(from, to int64) {
+	chats, err := b.chats.List()
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to list chats to migrate", "chat_id", from, "err", err)
+		return
+	}
+
+	var migrated []AugmentedChat
+	for _, chat := range chats {
+		if chat.ID != from {
+			continue
+		}
+		if err := b.chats.Remove(chat); err != nil {
+			level.Warn(b.logger).Log("msg", "failed to remove migrated chat", "chat_id", from, "err", err)
+			continue
+		}
+		chat.ID = to
+		chat.Type = botapi.ChatSupergroup
+		if err := b.chats.Add(chat); err != nil {
+			level.Warn(b.logger).Log("msg", "failed to add migrated chat", "chat_id", to, "err", err)
+			continue
+		}
+		migrated = append(migrated, chat)
+	}
+	if len(migrated) == 0 {
+		return
+	}
+	level.Info(b.logger).Log("msg", "migrated chat to supergroup", "from", from, "to", to)
+
+	if b.routes != nil {
+		routes, err := b.routes.List()
+		if err != nil {
+			level.Warn(b.logger).Log("msg", "failed to list routes to migrate", "chat_id", from, "err", err)
+		}
+		for _, r := range routes {
+			if !r.HasChat(from) {
+				continue
+			}
+			r.RemoveChat(from)
+			r.AddChat(to)
+			if err := b.routes.Add(r); err != nil {
+				level.Warn(b.logger).Log("msg", "failed to migrate route", "route", r.Name, "chat_id", from, "err", err)
+			}
+		}
+	}
+
+	if b.pins != nil {
+		b.migratePins(from, to)
+	}
+
+	b.mu.RLock()
+	for _, r := range b.staticRoutes {
+		if r.HasChat(from) {
+			level.Warn(b.logger).Log("msg", "migrated chat is part of a route in the config file, please replace its ID", "route", r.Name, "from", from, "to", to)
+		}
+	}
+	b.mu.RUnlock()
+
+	b.notifyAdmins(func(lang string) string {
+		return tr(lang, "Chat %s was upgraded to a supergroup, its subscription moved to chat %d.", chatTitle(lang, migrated[0]), to)
+	})
+}
+
+// migratePins moves the pinned messages of a group upgraded to a supergroup to the supergroup's ID,
+// so they're unpinned once their alert groups are resolved
+func (b *Bot) migratePins(from, to int64) {
+	pins, err := b.pins.List()
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "failed to list pins to migrate", "chat_id", from, "err", err)
+		return
+	}
+	for _, p := range pins {
+		if p.ChatID != from {
+			continue
+		}
+		if err := b.pins.Remove(p); err != nil {
+			level.Warn(b.logger).Log("msg", "failed to remove migrated pin", "chat_id", from, "err", err)
+			continue
+		}
+		p.ChatID = to
+		if err := b.pins.Add(p); err != nil {
+			level.Warn(b.logger).Log("msg", "failed to add migrated pin", "chat_id", to, "err", err)
+		}
+	}
+}
+
+// chatTitle returns the chat's name and ID for the admins
+func chatTitle(lang string, chat AugmentedChat) string {
+	name := chat.Username
+	if chat.IsGroupChat() {
+		name = chat.Title
+	}
+	if chat.ThreadID != 0 {
+		return tr(lang, "%s (%d, topic %d)", name, chat.ID, chat.ThreadID)
+	}
+	return fmt.Sprintf("%s (%d)", name, chat.ID)
+}
+
+// notifyAdmins sends the message to the private chats of the admins, in their chat's language if they're subscribed
+func (b *Bot) notifyAdmins(message func(lang string) string) {
+	b.mu.RLock()
+	admins := append([]int(nil), b.admins...)
+	b.mu.RUnlock()
+
+	for _, admin := range admins {
+		r := Recipient{ChatID: int64(admin)}
+
+		lang := DefaultLanguage
+		if chat, err := b.chats.Get(r); err == nil && chat.Language != "" {
+			lang = chat.Language
+		}
+		b.reply(r, message(lang))
+	}
+}
@@ -0,0 +1,98 @@
+package telegram
+
+import (
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/metalmatze/alertmanager-bot/pkg/botapi"
+	"github.com/metalmatze/alertmanager-bot/pkg/botapi/botapitest"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsUnreachable(t *testing.T) {
+	assert.True(t, isUnreachable(&botapi.Error{Code: 403, Description: "Forbidden: bot was blocked by the user"}))
+	assert.True(t, isUnreachable(&botapi.Error{Code: 403, Description: "Forbidden: bot was kicked from the supergroup chat"}))
+	assert.True(t, isUnreachable(&botapi.Error{Code: 403, Description: "Forbidden: user is deactivated"}))
+	assert.True(t, isUnreachable(&botapi.Error{Code: 400, Description: "Bad Request: chat not found"}))
+	assert.True(t, isUnreachable(&botapi.Error{Code: 400, Description: "Bad Request: message thread not found"}))
+	assert.False(t, isUnreachable(&botapi.Error{Code: 403, Description: "Forbidden: not enough rights to send text messages to the chat"}))
+	assert.False(t, isUnreachable(&botapi.Error{Code: 400, Description: "Bad Request: can't parse entities"}))
+	assert.False(t, isUnreachable(&botapi.Error{Code: 429, Description: "Too Many Requests: retry after 5", RetryAfter: 5}))
+	assert.False(t, isUnreachable(nil))
+}
+
+func TestSendNotificationUnreachable(t *testing.T) {
+	s := botapitest.NewServer()
+	defer s.Close()
+
+	s.Handle("sendMessage", func(r botapitest.Request) (interface{}, *botapi.Error) {
+		switch r.Param("chat_id") {
+		case "-1":
+			return nil, &botapi.Error{Code: 403, Description: "Forbidden: bot was kicked from the group chat"}
+		case "-2":
+			return nil, &botapi.Error{Code: 400, Description: "Bad Request: group chat was upgraded to a supergroup chat", MigrateToChatID: -1002}
+		case "-4":
+			return nil, &botapi.Error{Code: 403, Description: "Forbidden: not enough rights to send text messages to the chat"}
+		}
+		return botapi.Message{ID: 1}, nil
+	})
+
+	kicked := AugmentedChat{Chat: botapi.Chat{ID: -1, Type: botapi.ChatGroup, Title: "kicked"}}
+	upgraded := AugmentedChat{Chat: botapi.Chat{ID: -2, Type: botapi.ChatGroup, Title: "upgraded"}, Template: "short"}
+	restricted := AugmentedChat{Chat: botapi.Chat{ID: -4, Type: botapi.ChatGroup, Title: "restricted"}}
+	chats := memoryChats{}
+	chats.Add(kicked)
+	chats.Add(upgraded)
+	chats.Add(restricted)
+
+	routes := memoryRoutes{}
+	routes.Add(Route{Name: "team", ChatIDs: []int64{-2, -3}})
+
+	pins := memoryPins{}
+	pins.Add(Pin{GroupKey: "{}:{}", ChatID: -2, MessageID: 7})
+
+	b := &Bot{
+		logger:   log.NewNopLogger(),
+		telegram: s.Client(),
+		chats:    chats,
+		routes:   routes,
+		pins:     pins,
+		admins:   []int{42},
+		metrics:  testMetrics(t),
+	}
+
+	b.sendNotification(notification{chat: kicked, message: Message{Text: "firing"}})
+	assert.Len(t, chats, 2)
+
+	// the bot may be allowed to send messages again
+	b.sendNotification(notification{chat: restricted, message: Message{Text: "firing"}})
+	assert.Len(t, chats, 2)
+
+	b.sendNotification(notification{chat: upgraded, message: Message{Text: "firing"}})
+	assert.Len(t, chats, 2)
+	migrated, err := chats.Get(Recipient{ChatID: -1002})
+	assert.NoError(t, err)
+	assert.Equal(t, botapi.ChatSupergroup, migrated.Type)
+	assert.Equal(t, "short", migrated.Template)
+	assert.Equal(t, []int64{-3, -1002}, routes["team"].ChatIDs)
+	assert.Len(t, pins, 1)
+	pin, err := pins.Get(Recipient{ChatID: -1002}, "{}:{}")
+	assert.NoError(t, err)
+	assert.Equal(t, 7, pin.MessageID)
+
+	var sent, notices []string
+	for _, r := range s.Requests("sendMessage") {
+		if r.Param("chat_id") == "42" {
+			notices = append(notices, r.Param("text"))
+			continue
+		}
+		sent = append(sent, r.Param("chat_id"))
+	}
+	assert.Equal(t, []string{"-1", "-4", "-2", "-1002"}, sent)
+	assert.Equal(t, float64(2), testutil.ToFloat64(b.metrics.notifications.WithLabelValues(botapi.ChatGroup, "failure")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(b.metrics.notifications.WithLabelValues(botapi.ChatSupergroup, "success")))
+	assert.Len(t, notices, 2)
+	assert.Contains(t, notices[0], "kicked (-1)")
+	assert.Contains(t, notices[1], "moved to chat -1002")
+}
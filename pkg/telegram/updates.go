@@ -0,0 +1,145 @@
+package telegram
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/metalmatze/alertmanager-bot/pkg/botapi"
+)
+
+// secretTokenHeader carries the secret token passed to setWebhook in every update Telegram sends
+const secretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// pollTimeout is how long Telegram waits for new updates before answering getUpdates
+const pollTimeout = 30 * time.Second
+
+// WithUpdatesWebhook makes Telegram send updates to the URL instead of the bot polling them.
+// The updates have to be passed to HandleUpdate, Telegram sends them with the secret token,
+// a random one is used if it's empty.
+func WithUpdatesWebhook(u *url.URL, secretToken string) BotOption {
+	return func(b *Bot) {
+		b.updatesURL = u
+		b.updatesSecret = secretToken
+	}
+}
+
+// randomSecretToken returns a secret token for setWebhook
+func randomSecretToken() (string, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(token), nil
+}
+
+// receiveUpdates starts receiving updates until ctx is done, by registering the webhook or by polling them
+func (b *Bot) receiveUpdates(ctx context.Context) error {
+	if b.updatesURL != nil {
+		level.Info(b.logger).Log("msg", "receiving updates by webhook", "host", b.updatesURL.Host)
+		return b.telegram.SetWebhook(botapi.SetWebhookParams{
+			URL:            b.updatesURL.String(),
+			SecretToken:    b.updatesSecret,
+			AllowedUpdates: []string{"message", "callback_query"},
+		})
+	}
+
+	// Telegram refuses polling while a webhook is registered, like after switching from webhook mode
+	if err := b.telegram.DeleteWebhook(); err != nil {
+		return err
+	}
+	go b.pollUpdates(ctx)
+	return nil
+}
+
+// pollUpdates long polls updates until ctx is done, retrying failed requests after a second
+func (b *Bot) pollUpdates(ctx context.Context) {
+	var offset int64
+	for {
+		updates, err := b.telegram.GetUpdates(ctx, offset, pollTimeout)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			level.Warn(b.logger).Log("msg", "failed to get updates", "err", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+
+		for _, u := range updates {
+			// Telegram drops updates with IDs lower than the offset
+			offset = u.ID + 1
+			if !b.queueUpdate(ctx, u) {
+				return
+			}
+		}
+	}
+}
+
+// queueUpdate passes the update to the handlers, it returns false if ctx was done before
+func (b *Bot) queueUpdate(ctx context.Context, u botapi.Update) bool {
+	switch {
+	case u.Message != nil:
+		select {
+		case b.messages <- *u.Message:
+		case <-ctx.Done():
+			return false
+		}
+	case u.CallbackQuery != nil:
+		select {
+		case b.callbacks <- *u.CallbackQuery:
+		case <-ctx.Done():
+			return false
+		}
+	}
+	return true
+}
+
+// stopUpdates deletes the webhook so Telegram stops sending updates to it
+func (b *Bot) stopUpdates() {
+	if b.updatesURL == nil {
+		return
+	}
+	if err := b.telegram.DeleteWebhook(); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to delete webhook", "err", err)
+		return
+	}
+	level.Info(b.logger).Log("msg", "deleted webhook")
+}
+
+// HandleUpdate receives the updates Telegram sends to the webhook registered with WithUpdatesWebhook
+func (b *Bot) HandleUpdate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get(secretTokenHeader)), []byte(b.updatesSecret)) != 1 {
+		level.Warn(b.logger).Log("msg", "rejected update with invalid secret token", "remote", r.RemoteAddr)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var update botapi.Update
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		level.Warn(b.logger).Log("msg", "failed to decode update", "err", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// Telegram sends the update again if it isn't accepted, so it waits while the bot is busy
+	if !b.queueUpdate(r.Context(), update) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
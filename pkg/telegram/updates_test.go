@@ -0,0 +1,64 @@
+package telegram
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/metalmatze/alertmanager-bot/pkg/botapi"
+	"github.com/metalmatze/alertmanager-bot/pkg/botapi/botapitest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleUpdate(t *testing.T) {
+	b := &Bot{
+		logger:        log.NewNopLogger(),
+		updatesSecret: "secret",
+		messages:      make(chan botapi.Message, 1),
+		callbacks:     make(chan botapi.CallbackQuery, 1),
+	}
+
+	status := func(method string, secret string, body string) int {
+		req := httptest.NewRequest(method, "/telegram", strings.NewReader(body))
+		req.Header.Set(secretTokenHeader, secret)
+		rec := httptest.NewRecorder()
+		b.HandleUpdate(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusMethodNotAllowed, status(http.MethodGet, "secret", ""))
+	assert.Equal(t, http.StatusUnauthorized, status(http.MethodPost, "wrong", `{"update_id":1}`))
+	assert.Equal(t, http.StatusBadRequest, status(http.MethodPost, "secret", `{`))
+
+	assert.Equal(t, http.StatusOK, status(http.MethodPost, "secret", `{"update_id":1,"message":{"message_id":2,"text":"/status"}}`))
+	assert.Equal(t, "/status", (<-b.messages).Text)
+
+	assert.Equal(t, http.StatusOK, status(http.MethodPost, "secret", `{"update_id":2,"callback_query":{"id":"3","data":"alerts:2"}}`))
+	assert.Equal(t, "alerts:2", (<-b.callbacks).Data)
+}
+
+func TestPollUpdates(t *testing.T) {
+	s := botapitest.NewServer()
+	defer s.Close()
+
+	b := &Bot{
+		logger:    log.NewNopLogger(),
+		telegram:  s.Client(),
+		messages:  make(chan botapi.Message, 1),
+		callbacks: make(chan botapi.CallbackQuery, 1),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	assert.NoError(t, b.receiveUpdates(ctx))
+	assert.Len(t, s.Requests("deleteWebhook"), 1)
+
+	s.Update(botapi.Update{ID: 1, Message: &botapi.Message{ID: 2, Text: "/status"}})
+	s.Update(botapi.Update{ID: 2, CallbackQuery: &botapi.CallbackQuery{ID: "3", Data: "alerts:2"}})
+
+	assert.Equal(t, "/status", (<-b.messages).Text)
+	assert.Equal(t, "alerts:2", (<-b.callbacks).Data)
+}